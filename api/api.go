@@ -3,7 +3,6 @@ package api
 import (
 	"bytes"
 	"io"
-	"math/rand"
 	"net/http"
 	"strconv"
 	"strings"
@@ -16,9 +15,12 @@ import (
 	"github.com/u-speak/core/config"
 	"github.com/u-speak/core/img"
 	"github.com/u-speak/core/node"
-	"github.com/u-speak/core/post"
+	// Registers the post payload type
+	_ "github.com/u-speak/core/post"
 	"github.com/u-speak/core/tangle"
 	"github.com/u-speak/core/tangle/datastore"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/query"
 	"github.com/u-speak/core/tangle/site"
 
 	log "github.com/sirupsen/logrus"
@@ -27,6 +29,12 @@ import (
 const (
 	// MaxLatest is the highest limit amount for getRandom
 	MaxLatest = 100
+	// MaxDepth is the highest depth for getApprovers and getAncestors
+	MaxDepth = 10
+	// DefaultResults is the page size of getSearch if no limit is given
+	DefaultResults = 20
+	// MaxResults is the highest page size of getSearch
+	MaxResults = 100
 )
 
 // API is used as a container, allowing the REST API to access the node
@@ -47,17 +55,47 @@ type Error struct {
 	Code    int    `json:"code"`
 }
 
+// jsonSite is the representation of a site in all responses.
+// Weight is the number of leading zero bytes of the proof of work of the site, summed up with all sites approving it.
+// Difficulty is the same sum counted in leading zero bits.
 type jsonSite struct {
 	Nonce        uint64                 `json:"nonce"`
 	Validates    []string               `json:"validates"`
 	Hash         string                 `json:"hash"`
 	Content      string                 `json:"content"`
 	Type         string                 `json:"type"`
+	PoW          string                 `json:"pow,omitempty"`
+	Time         int64                  `json:"time,omitempty"`
 	BubbleBabble string                 `json:"bubblebabble"`
 	Weight       int                    `json:"weight"`
+	Difficulty   int                    `json:"difficulty"`
+	Confidence   float64                `json:"confidence"`
+	Confirmed    bool                   `json:"confirmed"`
 	Data         datastore.Serializable `json:"data"`
 }
 
+type jsonResult struct {
+	jsonSite
+	Score float64 `json:"score"`
+}
+
+type jsonDifficulty struct {
+	Difficulty int      `json:"difficulty"`
+	Base       int      `json:"base"`
+	Rate       int      `json:"rate"`
+	Payload    int      `json:"payload"`
+	Size       int      `json:"size"`
+	Allowance  int      `json:"allowance"`
+	Formula    string   `json:"formula"`
+	PoW        string   `json:"pow"`
+	Validates  []string `json:"validates"`
+}
+
+type jsonRelative struct {
+	Hash  string `json:"hash"`
+	Depth int    `json:"depth"`
+}
+
 // New returns a configured instance of the API server
 func New(c config.Configuration, n *node.Node) *API {
 	a := &API{
@@ -97,12 +135,15 @@ func (a *API) Run() error {
 
 	apiV1 := e.Group("/api/v1")
 	apiV1.GET("/status", a.getStatus)
+	apiV1.GET("/difficulty", a.getDifficulty)
 	apiV1.POST("/image", a.uploadImage)
 	apiV1.GET("/image/:hash", a.getImage)
 	apiV1.GET("/tangle", a.getSearch)
 	apiV1.GET("/tangle/random", a.getRandom)
 	apiV1.GET("/tangle/:hash", a.getSite)
 	apiV1.POST("/tangle/:hash", a.addSite)
+	apiV1.GET("/tangle/:hash/approvers", a.getApprovers)
+	apiV1.GET("/tangle/:hash/ancestors", a.getAncestors)
 	log.Infof("Starting API Server on interface %s", a.ListenInterface)
 	return e.StartTLS(a.ListenInterface, a.certfile, a.keyfile)
 }
@@ -116,29 +157,103 @@ func (a *API) getSite(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, Error{Message: "Invalid base64 data", Code: http.StatusBadRequest})
 	}
-	s := a.node.Tangle.Get(h)
-	if s == nil {
-		return c.JSON(http.StatusNotFound, Error{Message: "Site not found", Code: http.StatusNotFound})
+	s, err := a.node.Tangle.Get(h)
+	if err != nil {
+		return siteError(c, err)
 	}
 	err = s.Data.JSON()
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, Error{Message: "Error preparing response", Code: http.StatusInternalServerError})
 	}
 	j := JSONize(s)
-	j.Weight = a.node.Tangle.Weight(s.Site)
+	j.Weight = a.node.Tangle.ByteWeight(s.Site)
+	j.Difficulty = a.node.Tangle.Weight(s.Site)
+	j.Confidence = a.node.Tangle.Confidence(h)
+	j.Confirmed = j.Confidence >= a.node.Tangle.ConfirmationThreshold()
 	return c.JSON(http.StatusOK, j)
 }
 
+// getDifficulty returns the number of leading zero bits a new site validating the given sites
+// with a payload of the given size in bytes needs. Without validates parameters the recommended tips are used.
+func (a *API) getDifficulty(c echo.Context) error {
+	size := 0
+	if ss := c.QueryParam("size"); ss != "" {
+		var err error
+		size, err = strconv.Atoi(ss)
+		if err != nil || size < 0 {
+			return c.JSON(http.StatusBadRequest, Error{Message: "Invalid payload size", Code: http.StatusBadRequest})
+		}
+	}
+	hs := []hash.Hash{}
+	for _, v := range c.QueryParams()["validates"] {
+		h, err := DecodeHash(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Error{Message: "Invalid hash in validations: " + v, Code: http.StatusBadRequest})
+		}
+		hs = append(hs, h)
+	}
+	if len(hs) == 0 {
+		for _, s := range a.node.Tangle.RecommendTips() {
+			hs = append(hs, s.Hash())
+		}
+	}
+	vs := []string{}
+	for _, h := range hs {
+		vs = append(vs, h.String())
+	}
+	return c.JSON(http.StatusOK, jsonDifficulty{
+		Difficulty: a.node.Tangle.RequiredDifficulty(hs, size),
+		Base:       a.node.Tangle.Difficulty(),
+		Rate:       a.node.Tangle.RateDifficulty(hs),
+		Payload:    tangle.PayloadDifficulty(size),
+		Size:       size,
+		Allowance:  tangle.PayloadAllowance,
+		Formula:    "base + rate + ceil(log2(size / allowance)) for size > allowance",
+		PoW:        a.node.Tangle.PoW(),
+		Validates:  vs,
+	})
+}
+
+func (a *API) getApprovers(c echo.Context) error {
+	return a.getRelatives(c, a.node.Tangle.Approvers)
+}
+
+func (a *API) getAncestors(c echo.Context) error {
+	return a.getRelatives(c, func(h hash.Hash) []hash.Hash {
+		s, err := a.node.Tangle.GetSite(h)
+		if err != nil {
+			return nil
+		}
+		return s.Validates
+	})
+}
+
+// getRelatives lists the sites reachable through next, up to the requested depth
+func (a *API) getRelatives(c echo.Context, next func(hash.Hash) []hash.Hash) error {
+	h, err := DecodeHash(c.Param("hash"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Error{Message: "Invalid base64 data", Code: http.StatusBadRequest})
+	}
+	if _, err := a.node.Tangle.GetSite(h); err != nil {
+		return siteError(c, err)
+	}
+	depth := 1
+	if ds := c.QueryParam("depth"); ds != "" {
+		depth, err = strconv.Atoi(ds)
+		if err != nil || depth < 1 || depth > MaxDepth {
+			return c.JSON(http.StatusBadRequest, Error{Message: "Depth has to be between 1 and " + strconv.Itoa(MaxDepth), Code: http.StatusBadRequest})
+		}
+	}
+	return c.JSON(http.StatusOK, relatives(h, depth, next))
+}
+
 func (a *API) addSite(c echo.Context) error {
 	s := new(jsonSite)
-	switch c.Param("hash") {
-	case "post":
-		s.Data = &post.Post{}
-	case "image":
-		s.Data = &img.Image{}
-	default:
+	t, err := datastore.LookupPublic(c.Param("hash"))
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, Error{Message: "Invalid type parameter: " + c.Param("hash"), Code: http.StatusInternalServerError})
 	}
+	s.Data = t.New()
 	if err := c.Bind(s); err != nil {
 		return c.JSON(http.StatusBadRequest, Error{Message: err.Error(), Code: http.StatusBadRequest})
 	}
@@ -149,13 +264,6 @@ func (a *API) addSite(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, Error{Message: "Could not decode provided hash", Code: http.StatusBadRequest})
 	}
-	switch c.Param("hash") {
-	case "post":
-		err := verifyGPG(s.Data)
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, Error{Message: err.Error(), Code: http.StatusBadRequest})
-		}
-	}
 	o := &tangle.Object{Data: s.Data}
 	ch, err := DecodeHash(s.Content)
 	if err != nil {
@@ -166,24 +274,23 @@ func (a *API) addSite(c echo.Context) error {
 		log.Error(err)
 		return c.JSON(http.StatusBadRequest, Error{Message: "Content did not match supplied hash", Code: http.StatusBadRequest})
 	}
-	o.Site = &site.Site{Nonce: s.Nonce, Content: ch, Type: s.Type, Validates: []*site.Site{}}
+	o.Site = &site.Site{Nonce: s.Nonce, Content: ch, Type: t.Name, PoW: s.PoW, Time: s.Time, Validates: []hash.Hash{}}
 	for _, b64 := range s.Validates {
 		h, err := DecodeHash(b64)
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, Error{Message: "Invalid hash in validations: " + b64, Code: http.StatusBadRequest})
 		}
-		v := a.node.Tangle.Get(h)
-		if v == nil {
+		if !a.node.Tangle.Has(h) {
 			return c.JSON(http.StatusBadRequest, Error{Message: "Tried to verify unknown site " + b64, Code: http.StatusBadRequest})
 		}
-		o.Site.Validates = append(o.Site.Validates, v.Site)
+		o.Site.Validates = append(o.Site.Validates, h)
 	}
 	if o.Site.Hash() != sh {
 		return c.JSON(http.StatusBadRequest, Error{Message: "Provided hash does not match", Code: http.StatusBadRequest})
 	}
 	err = a.node.Submit(o)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, Error{Message: err.Error(), Code: http.StatusBadRequest})
+		return submitError(c, err)
 	}
 	return c.NoContent(http.StatusAccepted)
 }
@@ -196,6 +303,12 @@ func (a *API) uploadImage(c echo.Context) error {
 	}
 	o.Site.Nonce = nonce
 	o.Site.Type = "image"
+	if ts := c.FormValue("time"); ts != "" {
+		o.Site.Time, err = strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Error{Message: "Invalid field: Time", Code: http.StatusBadRequest})
+		}
+	}
 
 	vls := strings.Split(c.FormValue("validates"), ",")
 	for _, b64 := range vls {
@@ -203,11 +316,10 @@ func (a *API) uploadImage(c echo.Context) error {
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, Error{Message: "Invalid hash in validations: " + b64, Code: http.StatusBadRequest})
 		}
-		v := a.node.Tangle.Get(h)
-		if v == nil {
+		if !a.node.Tangle.Has(h) {
 			return c.JSON(http.StatusBadRequest, Error{Message: "Tried to verify unknown site " + b64, Code: http.StatusBadRequest})
 		}
-		o.Site.Validates = append(o.Site.Validates, v.Site)
+		o.Site.Validates = append(o.Site.Validates, h)
 	}
 	rh, err := DecodeHash(c.FormValue("hash"))
 	if err != nil {
@@ -236,14 +348,17 @@ func (a *API) uploadImage(c echo.Context) error {
 	}
 	err = a.node.Submit(o)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, Error{Message: err.Error(), Code: http.StatusBadRequest})
+		return submitError(c, err)
 	}
 	return c.NoContent(http.StatusAccepted)
 }
 
 func (a *API) getImage(c echo.Context) error {
 	h, t := decodeImageHash(c.Param("hash"))
-	s := a.node.Tangle.Get(h)
+	s, err := a.node.Tangle.Get(h)
+	if err != nil {
+		return siteError(c, err)
+	}
 	if s.Site.Type != "image" {
 		return c.JSON(http.StatusBadRequest, Error{Message: "requested site was not an image", Code: http.StatusBadRequest})
 	}
@@ -266,17 +381,35 @@ func (a *API) getImage(c echo.Context) error {
 }
 
 func (a *API) getSearch(c echo.Context) error {
-	results := []jsonSite{}
-	sr := a.node.Tangle.Search(c.QueryParam("q"))
-	if len(sr) == 0 {
-		return c.JSON(http.StatusNotFound, Error{Message: "No results found", Code: http.StatusNotFound})
+	q, err := query.Parse(c.QueryParam("q"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Error{Message: err.Error(), Code: http.StatusBadRequest})
+	}
+	order, err := query.ParseOrder(c.QueryParam("sort"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Error{Message: err.Error(), Code: http.StatusBadRequest})
+	}
+	limit := DefaultResults
+	if ls := c.QueryParam("limit"); ls != "" {
+		limit, err = strconv.Atoi(ls)
+		if err != nil || limit < 1 || limit > MaxResults {
+			return c.JSON(http.StatusBadRequest, Error{Message: "Limit has to be between 1 and " + strconv.Itoa(MaxResults), Code: http.StatusBadRequest})
+		}
+	}
+	sr := a.node.Tangle.Query(q, order)
+	p, next, err := paginate(sr, c.QueryParam("cursor"), limit)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Error{Message: err.Error(), Code: http.StatusBadRequest})
 	}
-	for _, o := range sr {
-		results = append(results, JSONize(o))
+	results := []jsonResult{}
+	for _, r := range p {
+		results = append(results, jsonResult{jsonSite: JSONize(r.Object), Score: r.Score})
 	}
 	return c.JSON(http.StatusOK, struct {
-		Results []jsonSite `json:"results"`
-	}{Results: results})
+		Results []jsonResult `json:"results"`
+		Total   int          `json:"total"`
+		Next    string       `json:"next,omitempty"`
+	}{Results: results, Total: len(sr), Next: next})
 }
 
 func (a *API) getRandom(c echo.Context) error {
@@ -288,16 +421,12 @@ func (a *API) getRandom(c echo.Context) error {
 			limit = ln
 		}
 	}
-	hs := a.node.Tangle.Hashes()
-	for i := range hs {
-		j := rand.Intn(i + 1)
-		hs[i], hs[j] = hs[j], hs[i]
+	hs, err := a.node.Tangle.Sample(limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Error{Message: err.Error(), Code: http.StatusInternalServerError})
 	}
 	res := []string{}
-	if limit > len(hs) {
-		limit = len(hs)
-	}
-	for _, h := range hs[:limit] {
+	for _, h := range hs {
 		res = append(res, h.String())
 	}
 	return c.JSON(http.StatusOK, res)
@@ -2,7 +2,15 @@ package api
 
 import (
 	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/u-speak/core/tangle"
+	"github.com/u-speak/core/tangle/datastore"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
 )
 
 var validHash = [32]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
@@ -46,3 +54,79 @@ func TestDecodeImageHash(t *testing.T) {
 		}
 	}
 }
+
+func TestRelatives(t *testing.T) {
+	a, b, c, d := hash.Hash{1}, hash.Hash{2}, hash.Hash{3}, hash.Hash{4}
+	edges := map[hash.Hash][]hash.Hash{a: {b, c}, b: {c, d}, c: {d}}
+	next := func(h hash.Hash) []hash.Hash { return edges[h] }
+
+	rs := relatives(a, 1, next)
+	if len(rs) != 2 || rs[0].Depth != 1 || rs[1].Depth != 1 {
+		t.Errorf("Expected two relatives at depth 1, got %v", rs)
+	}
+	rs = relatives(a, MaxDepth, next)
+	if len(rs) != 3 {
+		t.Errorf("Expected every relative once, got %v", rs)
+	}
+	if rs[2].Hash != d.String() || rs[2].Depth != 2 {
+		t.Errorf("Expected %s at depth 2, got %v", d.String(), rs[2])
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	rs := []tangle.Result{}
+	for i := 0; i < 5; i++ {
+		rs = append(rs, tangle.Result{Object: &tangle.Object{Site: &site.Site{Nonce: uint64(i)}}})
+	}
+	p, next, err := paginate(rs, "", 2)
+	if err != nil || len(p) != 2 || next != rs[1].Site.Hash().String() {
+		t.Errorf("Expected the first two results and a cursor, got %v %s %v", p, next, err)
+	}
+	p, next, err = paginate(rs, next, 2)
+	if err != nil || len(p) != 2 || p[0] != rs[2] || next != rs[3].Site.Hash().String() {
+		t.Errorf("Expected the next two results, got %v %s %v", p, next, err)
+	}
+	p, next, err = paginate(rs, next, 2)
+	if err != nil || len(p) != 1 || p[0] != rs[4] || next != "" {
+		t.Errorf("Expected the last result without cursor, got %v %s %v", p, next, err)
+	}
+	p, next, err = paginate([]tangle.Result{}, "", 2)
+	if err != nil || len(p) != 0 || next != "" {
+		t.Errorf("Expected an empty page, got %v %s %v", p, next, err)
+	}
+	_, _, err = paginate(rs, hash.Hash{1}.String(), 2)
+	if err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestSiteError(t *testing.T) {
+	e := echo.New()
+	for err, code := range map[error]int{
+		tangle.ErrNotFound:       http.StatusNotFound,
+		tangle.ErrCorrupt:        http.StatusInternalServerError,
+		datastore.ErrUnknownType: http.StatusInternalServerError,
+	} {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		if siteError(c, err) != nil || rec.Code != code {
+			t.Errorf("Expected %d for %v, got %d", code, err, rec.Code)
+		}
+	}
+}
+
+func TestSubmitError(t *testing.T) {
+	e := echo.New()
+	for err, code := range map[error]int{
+		tangle.ErrWeightTooLow:   http.StatusBadRequest,
+		tangle.ErrNoPayload:      http.StatusBadRequest,
+		datastore.ErrUnknownType: http.StatusBadRequest,
+		tangle.ErrCorrupt:        http.StatusInternalServerError,
+	} {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+		if submitError(c, err) != nil || rec.Code != code {
+			t.Errorf("Expected %d for %v, got %d", code, err, rec.Code)
+		}
+	}
+}
@@ -3,21 +3,24 @@ package api
 import (
 	"encoding/base64"
 	"errors"
+	"net/http"
 	"strings"
 
-	"github.com/u-speak/core/post"
+	"github.com/labstack/echo"
 	"github.com/u-speak/core/tangle"
-	"github.com/u-speak/core/tangle/datastore"
 	"github.com/u-speak/core/tangle/hash"
 	"github.com/u-speak/core/util"
 )
 
+// ErrInvalidCursor is returned for cursors not pointing to a result of the search
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
 // JSONize converts an object into a jsonSite
 func JSONize(o *tangle.Object) jsonSite {
 	h := o.Site.Hash()
 	vals := []string{}
 	for _, v := range o.Site.Validates {
-		vals = append(vals, v.Hash().String())
+		vals = append(vals, v.String())
 	}
 	return jsonSite{
 		Nonce:        o.Site.Nonce,
@@ -25,11 +28,79 @@ func JSONize(o *tangle.Object) jsonSite {
 		Validates:    vals,
 		Content:      o.Site.Content.String(),
 		Type:         o.Site.Type,
+		PoW:          o.Site.PoW,
+		Time:         o.Site.Time,
 		BubbleBabble: util.EncodeBubbleBabble(h),
 		Data:         o.Data,
 	}
 }
 
+// siteError responds to an error of Tangle.Get with 404 for unknown sites and 500 otherwise
+func siteError(c echo.Context, err error) error {
+	if err == tangle.ErrNotFound {
+		return c.JSON(http.StatusNotFound, Error{Message: "Site not found", Code: http.StatusNotFound})
+	}
+	return c.JSON(http.StatusInternalServerError, Error{Message: err.Error(), Code: http.StatusInternalServerError})
+}
+
+// submitError responds to an error of Node.Submit. Failed verifications are the fault of the client,
+// only a corrupt store is reported as 500
+func submitError(c echo.Context, err error) error {
+	if err == tangle.ErrCorrupt {
+		return c.JSON(http.StatusInternalServerError, Error{Message: err.Error(), Code: http.StatusInternalServerError})
+	}
+	return c.JSON(http.StatusBadRequest, Error{Message: err.Error(), Code: http.StatusBadRequest})
+}
+
+// relatives walks breadth first from h, returning every site reached within depth steps once
+func relatives(h hash.Hash, depth int, next func(hash.Hash) []hash.Hash) []jsonRelative {
+	res := []jsonRelative{}
+	seen := map[hash.Hash]bool{h: true}
+	bound := []hash.Hash{h}
+	for d := 1; d <= depth && len(bound) > 0; d++ {
+		nb := []hash.Hash{}
+		for _, b := range bound {
+			for _, r := range next(b) {
+				if seen[r] {
+					continue
+				}
+				seen[r] = true
+				nb = append(nb, r)
+				res = append(res, jsonRelative{Hash: r.String(), Depth: d})
+			}
+		}
+		bound = nb
+	}
+	return res
+}
+
+// paginate returns up to limit results following the one with the cursor hash, starting at the first result without cursor.
+// The returned cursor points to the last result of the page and is empty on the last page.
+func paginate(rs []tangle.Result, cursor string, limit int) ([]tangle.Result, string, error) {
+	start := 0
+	if cursor != "" {
+		c, err := DecodeHash(cursor)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		start = -1
+		for i, r := range rs {
+			if r.Site.Hash() == c {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+	end := start + limit
+	if end >= len(rs) {
+		return rs[start:], "", nil
+	}
+	return rs[start:end], rs[end-1].Site.Hash().String(), nil
+}
+
 func decodeImageHash(s string) (hash.Hash, string) {
 	a := strings.Split(s, ".")
 	h, _ := DecodeHash(a[0])
@@ -75,12 +146,3 @@ func DecodeHash(s string) (hash.Hash, error) {
 	}
 	return [32]byte{}, errors.New("Could not parse base64 data")
 }
-
-func verifyGPG(s datastore.Serializable) error {
-	err := s.ReInit()
-	if err != nil {
-		return err
-	}
-	_, err = s.(*post.Post).Verify()
-	return err
-}
@@ -14,8 +14,33 @@ type Configuration struct {
 		DNS     string `default:"discovery.uspeak.io"`
 	}
 	Storage struct {
+		Engine     string `default:"bolt" env:"STORAGE_ENGINE"`
 		DataPath   string `default:"/var/lib/uspeak/data.db" env:"DATA_PATH"`
 		TanglePath string `default:"/var/lib/uspeak/tangle.db" env:"TANGLE_PATH"`
+		OrphanPath string `default:"/var/lib/uspeak/orphans.db" env:"ORPHAN_PATH"`
+		IndexPath  string `default:"/var/lib/uspeak/index.db" env:"INDEX_PATH"`
+	}
+	Tangle struct {
+		TipSelection struct {
+			Strategy string  `default:"walk"`
+			Alpha    float64 `default:"0.5"`
+			Depth    int     `default:"10"`
+		}
+		Confirmation struct {
+			Samples   int     `default:"100"`
+			Threshold float64 `default:"0.95"`
+			Interval  string  `default:"10s"`
+		}
+		Orphans struct {
+			MaxSize int    `default:"10000"`
+			MaxAge  string `default:"24h"`
+		}
+	}
+	Network struct {
+		ID         string `default:"mainnet" env:"NETWORK_ID"`
+		Genesis    string `env:"NETWORK_GENESIS"`
+		Difficulty int    `default:"8" env:"NETWORK_DIFFICULTY"`
+		PoW        string `default:"blake2b" env:"NETWORK_POW"`
 	}
 	NodeNetwork struct {
 		Port      int    `default:"6969" env:"NODE_PORT"`
@@ -1,11 +1,17 @@
 package core
 
 import (
+	"errors"
+	"os"
+	"strconv"
+
 	"github.com/u-speak/core/api"
 	"github.com/u-speak/core/config"
 	"github.com/u-speak/core/diag"
 	"github.com/u-speak/core/minui"
 	"github.com/u-speak/core/node"
+	"github.com/u-speak/core/tangle"
+	"github.com/u-speak/core/tangle/index"
 	"github.com/u-speak/core/webserver"
 
 	log "github.com/sirupsen/logrus"
@@ -40,3 +46,113 @@ func RunMinUI(n *node.Node) {
 	s := minui.New(Config, n)
 	s.Run()
 }
+
+// RunFsck checks the configured tangle and data stores for inconsistencies.
+// With repair set, broken sites are quarantined and the tips are rebuilt.
+// The stores must not be in use by a running node.
+func RunFsck(repair bool) error {
+	t, err := openTangle()
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	r, err := t.Check(repair)
+	if err != nil {
+		return err
+	}
+	for _, p := range r.Problems {
+		log.Warnf("%s: %s", p.Hash, p.Err)
+	}
+	for _, h := range r.Quarantined {
+		log.Infof("Quarantined %s", h)
+	}
+	if r.TipsRebuilt {
+		log.Info("Rebuilt tips")
+	}
+	log.Infof("Checked %d sites, found %d problems", r.Sites, len(r.Problems))
+	if !r.OK() && !repair {
+		return errors.New("Found " + strconv.Itoa(len(r.Problems)) + " problems, run with repair to fix them")
+	}
+	return nil
+}
+
+// RunExport writes the configured tangle into an archive at path
+func RunExport(path string) error {
+	t, err := openTangle()
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	n, err := t.Export(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	log.Infof("Exported %d sites to %s", n, path)
+	return f.Close()
+}
+
+// RunImport adds the sites of the archive at path to the configured tangle.
+// An interrupted import is resumed by running it again.
+func RunImport(path string) error {
+	t, err := openTangle()
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := t.Import(f)
+	log.Infof("Imported %d sites from %s", n, path)
+	return err
+}
+
+// RunReindex rebuilds the search index of the configured tangle
+func RunReindex() error {
+	t, err := openTangle()
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	err = t.RebuildIndex()
+	if err != nil {
+		return err
+	}
+	log.Infof("Rebuilt search index for %d sites", t.Size())
+	return nil
+}
+
+// openTangle opens the configured stores without starting a node
+func openTangle() (*tangle.Tangle, error) {
+	bs, err := node.OpenStore(Config)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := index.NewBoltIndex(Config.Storage.IndexPath)
+	if err != nil {
+		bs.Close()
+		return nil, err
+	}
+	t, err := tangle.New(tangle.Options{
+		Store:      bs,
+		DataPath:   Config.Storage.DataPath,
+		Index:      idx,
+		Network:    Config.Network.ID,
+		Genesis:    tangle.NewGenesis(Config.Network.Genesis),
+		Difficulty: Config.Network.Difficulty,
+		PoW:        Config.Network.PoW,
+	})
+	if err != nil {
+		bs.Close()
+		idx.Close()
+		return nil, err
+	}
+	return t, nil
+}
@@ -46,30 +46,20 @@ func (s *Server) getIndex(c echo.Context) error {
 }
 
 func (s *Server) getGraph(c echo.Context) error {
-	hs := s.node.Tangle.Hashes()
-	bound := make(map[*site.Site]bool)
-	ti := s.node.Tangle.Tips()
-	for _, t := range ti {
-		bound[t] = true
-	}
-	excl := make(map[hash.Hash]bool)
-	edges := []edge{}
-	for len(bound) > 0 {
-		for s := range bound {
-			sh := s.Hash()
-			excl[sh] = true
-			delete(bound, s)
-			for _, v := range s.Validates {
-				edges = append(edges, edge{From: sh.String(), To: v.Hash().String()})
-				if !excl[v.Hash()] {
-					bound[v] = true
-				}
-			}
-		}
-	}
 	hss := []string{}
-	for _, h := range hs {
+	edges := []edge{}
+	err := s.node.Tangle.ForEach(func(h hash.Hash, st *site.Site) bool {
 		hss = append(hss, h.String())
+		if st == nil {
+			return true
+		}
+		for _, v := range st.Validates {
+			edges = append(edges, edge{From: h.String(), To: v.String()})
+		}
+		return true
+	})
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
 	}
 	return c.JSON(http.StatusOK, struct {
 		Nodes []string `json:"nodes"`
@@ -3,8 +3,8 @@ module github.com/u-speak/core
 go 1.12
 
 require (
-	github.com/coreos/bbolt v1.3.3
 	github.com/deckarep/golang-set v1.7.1
+	github.com/dgraph-io/badger v1.6.2
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
 	github.com/gernest/front v0.0.0-20181129160812-ed80ca338b88
 	github.com/golang/protobuf v1.3.2
@@ -13,6 +13,7 @@ require (
 	github.com/labstack/gommon v0.3.0 // indirect
 	github.com/martinlindhe/bubblebabble v0.0.0-20160819103256-a0a549d8557a
 	github.com/philhofer/fwd v1.0.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
 	github.com/russross/blackfriday v2.0.0+incompatible
 	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
 	github.com/sirupsen/logrus v1.4.2
@@ -20,6 +21,7 @@ require (
 	github.com/tinylib/msgp v1.1.0
 	github.com/u-speak/logrusmiddleware v0.0.0-20170810044617-fc67619f3b22
 	github.com/vmihailenco/msgpack v4.0.4+incompatible
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/crypto v0.0.0-20190820162420-60c769a6c586
 	golang.org/x/net v0.0.0-20190813141303-74dc4d7220e7
 	google.golang.org/grpc v1.23.0
@@ -11,9 +11,18 @@ import (
 	// Used for decoding
 	_ "image/png"
 
+	"github.com/u-speak/core/tangle/datastore"
 	"github.com/u-speak/core/tangle/hash"
 )
 
+func init() {
+	datastore.Register(datastore.Type{
+		Name:   "image",
+		New:    func() datastore.Serializable { return &Image{} },
+		Public: true,
+	})
+}
+
 // Image wraps the raw byte data of the image
 type Image struct {
 	Raw []byte
@@ -63,10 +63,13 @@ var FileTemplatesFooterHTMLTmpl = []byte("\x3c\x68\x72\x2f\x3e\x0a\x3c\x66\x6f\x
 var FileTemplatesHeaderHTMLTmpl = []byte("\x3c\x21\x64\x6f\x63\x74\x79\x70\x65\x20\x68\x74\x6d\x6c\x3e\x0a\x3c\x68\x74\x6d\x6c\x20\x6c\x61\x6e\x67\x3d\x22\x65\x6e\x22\x3e\x0a\x20\x20\x3c\x68\x65\x61\x64\x3e\x0a\x20\x20\x20\x20\x3c\x6d\x65\x74\x61\x20\x63\x68\x61\x72\x73\x65\x74\x3d\x22\x55\x54\x46\x2d\x38\x22\x2f\x3e\x0a\x20\x20\x20\x20\x3c\x74\x69\x74\x6c\x65\x3e\x4d\x69\x6e\x55\x49\x3c\x2f\x74\x69\x74\x6c\x65\x3e\x0a\x20\x20\x20\x20\x3c\x6c\x69\x6e\x6b\x20\x68\x72\x65\x66\x3d\x22\x2f\x73\x74\x61\x74\x69\x63\x2f\x6e\x6f\x72\x6d\x61\x6c\x69\x7a\x65\x2e\x63\x73\x73\x22\x20\x72\x65\x6c\x3d\x22\x73\x74\x79\x6c\x65\x73\x68\x65\x65\x74\x22\x2f\x3e\x0a\x20\x20\x20\x20\x3c\x6c\x69\x6e\x6b\x20\x68\x72\x65\x66\x3d\x22\x2f\x73\x74\x61\x74\x69\x63\x2f\x7b\x7b\x2e\x54\x68\x65\x6d\x65\x7d\x7d\x2e\x63\x73\x73\x22\x20\x72\x65\x6c\x3d\x22\x73\x74\x79\x6c\x65\x73\x68\x65\x65\x74\x22\x2f\x3e\x0a\x20\x20\x20\x20\x3c\x6c\x69\x6e\x6b\x20\x68\x72\x65\x66\x3d\x22\x2f\x73\x74\x61\x74\x69\x63\x2f\x73\x74\x79\x6c\x65\x2e\x63\x73\x73\x22\x20\x72\x65\x6c\x3d\x22\x73\x74\x79\x6c\x65\x73\x68\x65\x65\x74\x22\x2f\x3e\x0a\x20\x20\x3c\x2f\x68\x65\x61\x64\x3e\x0a\x20\x20\x3c\x62\x6f\x64\x79\x3e\x0a")
 
 // FileTemplatesIndexHTMLTmpl is "templates/index.html.tmpl"
-var FileTemplatesIndexHTMLTmpl = []byte("\x7b\x7b\x20\x74\x65\x6d\x70\x6c\x61\x74\x65\x20\x22\x74\x65\x6d\x70\x6c\x61\x74\x65\x73\x2f\x68\x65\x61\x64\x65\x72\x2e\x68\x74\x6d\x6c\x2e\x74\x6d\x70\x6c\x22\x20\x2e\x20\x7d\x7d\x0a\x3c\x68\x31\x3e\x4d\x69\x6e\x55\x49\x3c\x2f\x68\x31\x3e\x0a\x3c\x68\x35\x3e\x42\x72\x6f\x75\x67\x68\x74\x20\x74\x6f\x20\x79\x6f\x75\x20\x62\x79\x3a\x20\x3c\x69\x3e\x7b\x7b\x20\x2e\x4d\x65\x73\x73\x61\x67\x65\x7d\x7d\x3c\x2f\x69\x3e\x3c\x2f\x68\x35\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x3c\x68\x36\x3e\x52\x65\x63\x65\x6e\x74\x20\x50\x6f\x73\x74\x73\x3a\x3c\x2f\x68\x36\x3e\x0a\x3c\x75\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x6f\x73\x74\x2d\x6c\x69\x73\x74\x22\x3e\x0a\x20\x20\x7b\x7b\x20\x72\x61\x6e\x67\x65\x20\x24\x6b\x65\x79\x2c\x20\x24\x76\x61\x6c\x75\x65\x20\x3a\x3d\x20\x2e\x44\x61\x74\x61\x20\x7d\x7d\x0a\x20\x20\x7b\x7b\x20\x69\x66\x20\x65\x71\x20\x28\x24\x76\x61\x6c\x75\x65\x2e\x53\x69\x74\x65\x2e\x54\x79\x70\x65\x29\x20\x22\x70\x6f\x73\x74\x22\x20\x7d\x7d\x0a\x20\x20\x3c\x6c\x69\x3e\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x70\x6f\x73\x74\x73\x2f\x7b\x7b\x20\x24\x76\x61\x6c\x75\x65\x2e\x53\x69\x74\x65\x2e\x48\x61\x73\x68\x20\x7d\x7d\x22\x3e\x7b\x7b\x20\x24\x76\x61\x6c\x75\x65\x2e\x44\x61\x74\x61\x20\x7c\x20\x50\x6f\x73\x74\x20\x7c\x20\x54\x69\x74\x6c\x65\x20\x7d\x7d\x3c\x2f\x61\x3e\x3c\x2f\x6c\x69\x3e\x0a\x20\x20\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x20\x20\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x3c\x2f\x75\x6c\x3e\x0a\x7b\x7b\x20\x74\x65\x6d\x70\x6c\x61\x74\x65\x20\x22\x74\x65\x6d\x70\x6c\x61\x74\x65\x73\x2f\x66\x6f\x6f\x74\x65\x72\x2e\x68\x74\x6d\x6c\x2e\x74\x6d\x70\x6c\x22\x20\x2e\x20\x7d\x7d\x0a\x20\x20\x20\x20\x0a")
+var FileTemplatesIndexHTMLTmpl = []byte("\x7b\x7b\x20\x74\x65\x6d\x70\x6c\x61\x74\x65\x20\x22\x74\x65\x6d\x70\x6c\x61\x74\x65\x73\x2f\x68\x65\x61\x64\x65\x72\x2e\x68\x74\x6d\x6c\x2e\x74\x6d\x70\x6c\x22\x20\x2e\x20\x7d\x7d\x0a\x3c\x68\x31\x3e\x4d\x69\x6e\x55\x49\x3c\x2f\x68\x31\x3e\x0a\x3c\x68\x35\x3e\x42\x72\x6f\x75\x67\x68\x74\x20\x74\x6f\x20\x79\x6f\x75\x20\x62\x79\x3a\x20\x3c\x69\x3e\x7b\x7b\x20\x2e\x4d\x65\x73\x73\x61\x67\x65\x7d\x7d\x3c\x2f\x69\x3e\x3c\x2f\x68\x35\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x3c\x66\x6f\x72\x6d\x20\x61\x63\x74\x69\x6f\x6e\x3d\x22\x2f\x73\x65\x61\x72\x63\x68\x22\x20\x6d\x65\x74\x68\x6f\x64\x3d\x22\x67\x65\x74\x22\x3e\x0a\x20\x20\x3c\x69\x6e\x70\x75\x74\x20\x74\x79\x70\x65\x3d\x22\x74\x65\x78\x74\x22\x20\x6e\x61\x6d\x65\x3d\x22\x71\x22\x20\x70\x6c\x61\x63\x65\x68\x6f\x6c\x64\x65\x72\x3d\x22\x53\x65\x61\x72\x63\x68\x22\x2f\x3e\x0a\x20\x20\x3c\x69\x6e\x70\x75\x74\x20\x74\x79\x70\x65\x3d\x22\x73\x75\x62\x6d\x69\x74\x22\x20\x76\x61\x6c\x75\x65\x3d\x22\x53\x65\x61\x72\x63\x68\x22\x2f\x3e\x0a\x3c\x2f\x66\x6f\x72\x6d\x3e\x0a\x3c\x68\x36\x3e\x52\x65\x63\x65\x6e\x74\x20\x50\x6f\x73\x74\x73\x3a\x3c\x2f\x68\x36\x3e\x0a\x3c\x75\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x6f\x73\x74\x2d\x6c\x69\x73\x74\x22\x3e\x0a\x20\x20\x7b\x7b\x20\x72\x61\x6e\x67\x65\x20\x24\x6b\x65\x79\x2c\x20\x24\x76\x61\x6c\x75\x65\x20\x3a\x3d\x20\x2e\x44\x61\x74\x61\x20\x7d\x7d\x0a\x20\x20\x7b\x7b\x20\x69\x66\x20\x65\x71\x20\x28\x24\x76\x61\x6c\x75\x65\x2e\x53\x69\x74\x65\x2e\x54\x79\x70\x65\x29\x20\x22\x70\x6f\x73\x74\x22\x20\x7d\x7d\x0a\x20\x20\x3c\x6c\x69\x3e\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x70\x6f\x73\x74\x73\x2f\x7b\x7b\x20\x24\x76\x61\x6c\x75\x65\x2e\x53\x69\x74\x65\x2e\x48\x61\x73\x68\x20\x7d\x7d\x22\x3e\x7b\x7b\x20\x24\x76\x61\x6c\x75\x65\x2e\x44\x61\x74\x61\x20\x7c\x20\x50\x6f\x73\x74\x20\x7c\x20\x54\x69\x74\x6c\x65\x20\x7d\x7d\x3c\x2f\x61\x3e\x3c\x2f\x6c\x69\x3e\x0a\x20\x20\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x20\x20\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x3c\x2f\x75\x6c\x3e\x0a\x7b\x7b\x20\x74\x65\x6d\x70\x6c\x61\x74\x65\x20\x22\x74\x65\x6d\x70\x6c\x61\x74\x65\x73\x2f\x66\x6f\x6f\x74\x65\x72\x2e\x68\x74\x6d\x6c\x2e\x74\x6d\x70\x6c\x22\x20\x2e\x20\x7d\x7d\x0a\x20\x20\x20\x20\x0a")
 
 // FileTemplatesPostHTMLTmpl is "templates/post.html.tmpl"
-var FileTemplatesPostHTMLTmpl = []byte("\x7b\x7b\x20\x74\x65\x6d\x70\x6c\x61\x74\x65\x20\x22\x74\x65\x6d\x70\x6c\x61\x74\x65\x73\x2f\x68\x65\x61\x64\x65\x72\x2e\x68\x74\x6d\x6c\x2e\x74\x6d\x70\x6c\x22\x20\x2e\x20\x7d\x7d\x0a\x0a\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x22\x3e\x48\x6f\x6d\x65\x3c\x2f\x61\x3e\x0a\x7b\x7b\x20\x69\x66\x20\x6e\x6f\x74\x20\x28\x2e\x44\x61\x74\x61\x20\x7c\x20\x56\x61\x6c\x69\x64\x29\x20\x7d\x7d\x0a\x45\x52\x52\x4f\x52\x3a\x20\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x56\x65\x72\x69\x66\x79\x20\x7d\x7d\x0a\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x4a\x53\x4f\x4e\x20\x7d\x7d\x0a\x3c\x70\x72\x65\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x3c\x63\x6f\x64\x65\x3e\x0a\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x53\x69\x67\x53\x74\x72\x20\x7d\x7d\x0a\x3c\x2f\x63\x6f\x64\x65\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x3c\x63\x6f\x64\x65\x3e\x0a\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x50\x75\x62\x6b\x65\x79\x53\x74\x72\x20\x7d\x7d\x0a\x3c\x2f\x63\x6f\x64\x65\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x3c\x63\x6f\x64\x65\x3e\x0a\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x43\x6f\x6e\x74\x65\x6e\x74\x20\x7d\x7d\x0a\x3c\x2f\x63\x6f\x64\x65\x3e\x0a\x3c\x2f\x70\x72\x65\x3e\x0a\x0a\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x74\x69\x74\x6c\x65\x22\x20\x73\x74\x79\x6c\x65\x3d\x22\x62\x61\x63\x6b\x67\x72\x6f\x75\x6e\x64\x3a\x20\x72\x67\x62\x61\x28\x30\x2c\x20\x30\x2c\x20\x30\x2c\x20\x30\x29\x20\x20\x75\x72\x6c\x28\x7b\x7b\x20\x2e\x44\x61\x74\x61\x20\x7c\x20\x49\x6d\x61\x67\x65\x20\x7d\x7d\x29\x20\x6e\x6f\x2d\x72\x65\x70\x65\x61\x74\x20\x73\x63\x72\x6f\x6c\x6c\x20\x63\x65\x6e\x74\x65\x72\x20\x63\x65\x6e\x74\x65\x72\x20\x2f\x20\x63\x6f\x76\x65\x72\x3b\x22\x3e\x0a\x3c\x68\x31\x3e\x7b\x7b\x20\x2e\x44\x61\x74\x61\x20\x7c\x20\x54\x69\x74\x6c\x65\x20\x7d\x7d\x3c\x2f\x68\x31\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x70\x3e\x0a\x20\x20\x7b\x7b\x20\x2e\x44\x61\x74\x61\x20\x7c\x20\x42\x6f\x64\x79\x20\x7c\x20\x4d\x61\x72\x6b\x64\x6f\x77\x6e\x20\x7d\x7d\x0a\x3c\x2f\x70\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x3c\x64\x69\x76\x3e\x0a\x20\x20\x7b\x7b\x20\x69\x66\x20\x2e\x44\x61\x74\x61\x20\x7c\x20\x56\x61\x6c\x69\x64\x20\x7d\x7d\x0a\x20\x20\x54\x68\x69\x73\x20\x70\x6f\x73\x74\x20\x68\x61\x73\x20\x62\x65\x65\x6e\x20\x63\x72\x79\x70\x74\x6f\x67\x72\x61\x70\x68\x69\x63\x61\x6c\x6c\x79\x20\x73\x69\x67\x6e\x65\x64\x20\x62\x79\x3a\x0a\x20\x20\x3c\x75\x6c\x3e\x0a\x20\x20\x20\x20\x7b\x7b\x20\x72\x61\x6e\x67\x65\x20\x24\x6b\x65\x79\x2c\x20\x24\x76\x61\x6c\x75\x65\x20\x3a\x3d\x20\x2e\x44\x61\x74\x61\x2e\x50\x75\x62\x6b\x65\x79\x2e\x49\x64\x65\x6e\x74\x69\x74\x69\x65\x73\x20\x7d\x7d\x0a\x20\x20\x20\x20\x3c\x6c\x69\x3e\x7b\x7b\x20\x24\x76\x61\x6c\x75\x65\x2e\x4e\x61\x6d\x65\x20\x7d\x7d\x3c\x2f\x6c\x69\x3e\x0a\x20\x20\x20\x20\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x20\x20\x3c\x2f\x75\x6c\x3e\x0a\x20\x20\x4b\x65\x79\x20\x46\x69\x6e\x67\x65\x72\x70\x72\x69\x6e\x74\x3a\x20\x3c\x63\x6f\x64\x65\x3e\x7b\x7b\x20\x2e\x44\x61\x74\x61\x20\x7c\x20\x46\x69\x6e\x67\x65\x72\x70\x72\x69\x6e\x74\x20\x7d\x7d\x3c\x2f\x63\x6f\x64\x65\x3e\x0a\x20\x20\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x7b\x7b\x20\x74\x65\x6d\x70\x6c\x61\x74\x65\x20\x22\x74\x65\x6d\x70\x6c\x61\x74\x65\x73\x2f\x66\x6f\x6f\x74\x65\x72\x2e\x68\x74\x6d\x6c\x2e\x74\x6d\x70\x6c\x22\x20\x2e\x20\x7d\x7d\x0a")
+var FileTemplatesPostHTMLTmpl = []byte("\x7b\x7b\x20\x74\x65\x6d\x70\x6c\x61\x74\x65\x20\x22\x74\x65\x6d\x70\x6c\x61\x74\x65\x73\x2f\x68\x65\x61\x64\x65\x72\x2e\x68\x74\x6d\x6c\x2e\x74\x6d\x70\x6c\x22\x20\x2e\x20\x7d\x7d\x0a\x0a\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x22\x3e\x48\x6f\x6d\x65\x3c\x2f\x61\x3e\x0a\x7b\x7b\x20\x69\x66\x20\x6e\x6f\x74\x20\x28\x2e\x44\x61\x74\x61\x2e\x50\x6f\x73\x74\x20\x7c\x20\x56\x61\x6c\x69\x64\x29\x20\x7d\x7d\x0a\x45\x52\x52\x4f\x52\x3a\x20\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x56\x65\x72\x69\x66\x79\x20\x7d\x7d\x0a\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x4a\x53\x4f\x4e\x20\x7d\x7d\x0a\x3c\x70\x72\x65\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x3c\x63\x6f\x64\x65\x3e\x0a\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x53\x69\x67\x53\x74\x72\x20\x7d\x7d\x0a\x3c\x2f\x63\x6f\x64\x65\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x3c\x63\x6f\x64\x65\x3e\x0a\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x50\x75\x62\x6b\x65\x79\x53\x74\x72\x20\x7d\x7d\x0a\x3c\x2f\x63\x6f\x64\x65\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x3c\x63\x6f\x64\x65\x3e\x0a\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x43\x6f\x6e\x74\x65\x6e\x74\x20\x7d\x7d\x0a\x3c\x2f\x63\x6f\x64\x65\x3e\x0a\x3c\x2f\x70\x72\x65\x3e\x0a\x0a\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x74\x69\x74\x6c\x65\x22\x20\x73\x74\x79\x6c\x65\x3d\x22\x62\x61\x63\x6b\x67\x72\x6f\x75\x6e\x64\x3a\x20\x72\x67\x62\x61\x28\x30\x2c\x20\x30\x2c\x20\x30\x2c\x20\x30\x29\x20\x20\x75\x72\x6c\x28\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x50\x6f\x73\x74\x20\x7c\x20\x49\x6d\x61\x67\x65\x20\x7d\x7d\x29\x20\x6e\x6f\x2d\x72\x65\x70\x65\x61\x74\x20\x73\x63\x72\x6f\x6c\x6c\x20\x63\x65\x6e\x74\x65\x72\x20\x63\x65\x6e\x74\x65\x72\x20\x2f\x20\x63\x6f\x76\x65\x72\x3b\x22\x3e\x0a\x3c\x68\x31\x3e\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x50\x6f\x73\x74\x20\x7c\x20\x54\x69\x74\x6c\x65\x20\x7d\x7d\x3c\x2f\x68\x31\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x70\x3e\x0a\x20\x20\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x50\x6f\x73\x74\x20\x7c\x20\x42\x6f\x64\x79\x20\x7c\x20\x4d\x61\x72\x6b\x64\x6f\x77\x6e\x20\x7d\x7d\x0a\x3c\x2f\x70\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x3c\x64\x69\x76\x3e\x0a\x20\x20\x7b\x7b\x20\x69\x66\x20\x2e\x44\x61\x74\x61\x2e\x50\x6f\x73\x74\x20\x7c\x20\x56\x61\x6c\x69\x64\x20\x7d\x7d\x0a\x20\x20\x54\x68\x69\x73\x20\x70\x6f\x73\x74\x20\x68\x61\x73\x20\x62\x65\x65\x6e\x20\x63\x72\x79\x70\x74\x6f\x67\x72\x61\x70\x68\x69\x63\x61\x6c\x6c\x79\x20\x73\x69\x67\x6e\x65\x64\x20\x62\x79\x3a\x0a\x20\x20\x3c\x75\x6c\x3e\x0a\x20\x20\x20\x20\x7b\x7b\x20\x72\x61\x6e\x67\x65\x20\x24\x6b\x65\x79\x2c\x20\x24\x76\x61\x6c\x75\x65\x20\x3a\x3d\x20\x2e\x44\x61\x74\x61\x2e\x50\x75\x62\x6b\x65\x79\x2e\x49\x64\x65\x6e\x74\x69\x74\x69\x65\x73\x20\x7d\x7d\x0a\x20\x20\x20\x20\x3c\x6c\x69\x3e\x7b\x7b\x20\x24\x76\x61\x6c\x75\x65\x2e\x4e\x61\x6d\x65\x20\x7d\x7d\x3c\x2f\x6c\x69\x3e\x0a\x20\x20\x20\x20\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x20\x20\x3c\x2f\x75\x6c\x3e\x0a\x20\x20\x4b\x65\x79\x20\x46\x69\x6e\x67\x65\x72\x70\x72\x69\x6e\x74\x3a\x20\x3c\x63\x6f\x64\x65\x3e\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x50\x6f\x73\x74\x20\x7c\x20\x46\x69\x6e\x67\x65\x72\x70\x72\x69\x6e\x74\x20\x7d\x7d\x3c\x2f\x63\x6f\x64\x65\x3e\x0a\x20\x20\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x64\x69\x76\x3e\x0a\x20\x20\x43\x6f\x6e\x66\x69\x64\x65\x6e\x63\x65\x3a\x20\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x43\x6f\x6e\x66\x69\x64\x65\x6e\x63\x65\x20\x7c\x20\x50\x65\x72\x63\x65\x6e\x74\x20\x7d\x7d\x0a\x20\x20\x7b\x7b\x20\x69\x66\x20\x2e\x44\x61\x74\x61\x2e\x43\x6f\x6e\x66\x69\x72\x6d\x65\x64\x20\x7d\x7d\x28\x63\x6f\x6e\x66\x69\x72\x6d\x65\x64\x29\x7b\x7b\x20\x65\x6c\x73\x65\x20\x7d\x7d\x28\x70\x65\x6e\x64\x69\x6e\x67\x29\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x7b\x7b\x20\x74\x65\x6d\x70\x6c\x61\x74\x65\x20\x22\x74\x65\x6d\x70\x6c\x61\x74\x65\x73\x2f\x66\x6f\x6f\x74\x65\x72\x2e\x68\x74\x6d\x6c\x2e\x74\x6d\x70\x6c\x22\x20\x2e\x20\x7d\x7d\x0a")
+
+// FileTemplatesSearchHTMLTmpl is "templates/search.html.tmpl"
+var FileTemplatesSearchHTMLTmpl = []byte("\x7b\x7b\x20\x74\x65\x6d\x70\x6c\x61\x74\x65\x20\x22\x74\x65\x6d\x70\x6c\x61\x74\x65\x73\x2f\x68\x65\x61\x64\x65\x72\x2e\x68\x74\x6d\x6c\x2e\x74\x6d\x70\x6c\x22\x20\x2e\x20\x7d\x7d\x0a\x3c\x68\x31\x3e\x53\x65\x61\x72\x63\x68\x3c\x2f\x68\x31\x3e\x0a\x3c\x66\x6f\x72\x6d\x20\x61\x63\x74\x69\x6f\x6e\x3d\x22\x2f\x73\x65\x61\x72\x63\x68\x22\x20\x6d\x65\x74\x68\x6f\x64\x3d\x22\x67\x65\x74\x22\x3e\x0a\x20\x20\x3c\x69\x6e\x70\x75\x74\x20\x74\x79\x70\x65\x3d\x22\x74\x65\x78\x74\x22\x20\x6e\x61\x6d\x65\x3d\x22\x71\x22\x20\x76\x61\x6c\x75\x65\x3d\x22\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x51\x75\x65\x72\x79\x20\x7d\x7d\x22\x20\x70\x6c\x61\x63\x65\x68\x6f\x6c\x64\x65\x72\x3d\x22\x74\x61\x67\x3a\x6e\x65\x77\x73\x20\x41\x4e\x44\x20\x28\x61\x75\x74\x68\x6f\x72\x3a\x31\x41\x32\x42\x33\x43\x34\x44\x20\x4f\x52\x20\x26\x71\x75\x6f\x74\x3b\x68\x65\x6c\x6c\x6f\x20\x77\x6f\x72\x6c\x64\x26\x71\x75\x6f\x74\x3b\x29\x22\x2f\x3e\x0a\x20\x20\x3c\x73\x65\x6c\x65\x63\x74\x20\x6e\x61\x6d\x65\x3d\x22\x73\x6f\x72\x74\x22\x3e\x0a\x20\x20\x20\x20\x3c\x6f\x70\x74\x69\x6f\x6e\x20\x76\x61\x6c\x75\x65\x3d\x22\x22\x20\x7b\x7b\x20\x69\x66\x20\x65\x71\x20\x2e\x44\x61\x74\x61\x2e\x53\x6f\x72\x74\x20\x22\x22\x20\x7d\x7d\x73\x65\x6c\x65\x63\x74\x65\x64\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x3e\x52\x65\x6c\x65\x76\x61\x6e\x63\x65\x3c\x2f\x6f\x70\x74\x69\x6f\x6e\x3e\x0a\x20\x20\x20\x20\x3c\x6f\x70\x74\x69\x6f\x6e\x20\x76\x61\x6c\x75\x65\x3d\x22\x64\x61\x74\x65\x22\x20\x7b\x7b\x20\x69\x66\x20\x65\x71\x20\x2e\x44\x61\x74\x61\x2e\x53\x6f\x72\x74\x20\x22\x64\x61\x74\x65\x22\x20\x7d\x7d\x73\x65\x6c\x65\x63\x74\x65\x64\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x3e\x4e\x65\x77\x65\x73\x74\x3c\x2f\x6f\x70\x74\x69\x6f\x6e\x3e\x0a\x20\x20\x20\x20\x3c\x6f\x70\x74\x69\x6f\x6e\x20\x76\x61\x6c\x75\x65\x3d\x22\x77\x65\x69\x67\x68\x74\x22\x20\x7b\x7b\x20\x69\x66\x20\x65\x71\x20\x2e\x44\x61\x74\x61\x2e\x53\x6f\x72\x74\x20\x22\x77\x65\x69\x67\x68\x74\x22\x20\x7d\x7d\x73\x65\x6c\x65\x63\x74\x65\x64\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x3e\x57\x65\x69\x67\x68\x74\x3c\x2f\x6f\x70\x74\x69\x6f\x6e\x3e\x0a\x20\x20\x3c\x2f\x73\x65\x6c\x65\x63\x74\x3e\x0a\x20\x20\x3c\x69\x6e\x70\x75\x74\x20\x74\x79\x70\x65\x3d\x22\x73\x75\x62\x6d\x69\x74\x22\x20\x76\x61\x6c\x75\x65\x3d\x22\x53\x65\x61\x72\x63\x68\x22\x2f\x3e\x0a\x3c\x2f\x66\x6f\x72\x6d\x3e\x0a\x3c\x68\x72\x2f\x3e\x0a\x7b\x7b\x20\x69\x66\x20\x2e\x44\x61\x74\x61\x2e\x45\x72\x72\x6f\x72\x20\x7d\x7d\x0a\x3c\x70\x3e\x3c\x62\x3e\x7b\x7b\x20\x2e\x44\x61\x74\x61\x2e\x45\x72\x72\x6f\x72\x20\x7d\x7d\x3c\x2f\x62\x3e\x3c\x2f\x70\x3e\x0a\x7b\x7b\x20\x65\x6c\x73\x65\x20\x7d\x7d\x0a\x3c\x75\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x6f\x73\x74\x2d\x6c\x69\x73\x74\x22\x3e\x0a\x20\x20\x7b\x7b\x20\x72\x61\x6e\x67\x65\x20\x24\x6b\x65\x79\x2c\x20\x24\x76\x61\x6c\x75\x65\x20\x3a\x3d\x20\x2e\x44\x61\x74\x61\x2e\x52\x65\x73\x75\x6c\x74\x73\x20\x7d\x7d\x0a\x20\x20\x7b\x7b\x20\x69\x66\x20\x65\x71\x20\x28\x24\x76\x61\x6c\x75\x65\x2e\x53\x69\x74\x65\x2e\x54\x79\x70\x65\x29\x20\x22\x70\x6f\x73\x74\x22\x20\x7d\x7d\x0a\x20\x20\x3c\x6c\x69\x3e\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x70\x6f\x73\x74\x73\x2f\x7b\x7b\x20\x24\x76\x61\x6c\x75\x65\x2e\x53\x69\x74\x65\x2e\x48\x61\x73\x68\x20\x7d\x7d\x22\x3e\x7b\x7b\x20\x24\x76\x61\x6c\x75\x65\x2e\x44\x61\x74\x61\x20\x7c\x20\x50\x6f\x73\x74\x20\x7c\x20\x54\x69\x74\x6c\x65\x20\x7d\x7d\x3c\x2f\x61\x3e\x3c\x2f\x6c\x69\x3e\x0a\x20\x20\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x20\x20\x7b\x7b\x20\x65\x6c\x73\x65\x20\x7d\x7d\x0a\x20\x20\x3c\x6c\x69\x3e\x4e\x6f\x20\x72\x65\x73\x75\x6c\x74\x73\x20\x66\x6f\x75\x6e\x64\x3c\x2f\x6c\x69\x3e\x0a\x20\x20\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x3c\x2f\x75\x6c\x3e\x0a\x7b\x7b\x20\x65\x6e\x64\x20\x7d\x7d\x0a\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x22\x3e\x42\x61\x63\x6b\x3c\x2f\x61\x3e\x0a\x7b\x7b\x20\x74\x65\x6d\x70\x6c\x61\x74\x65\x20\x22\x74\x65\x6d\x70\x6c\x61\x74\x65\x73\x2f\x66\x6f\x6f\x74\x65\x72\x2e\x68\x74\x6d\x6c\x2e\x74\x6d\x70\x6c\x22\x20\x2e\x20\x7d\x7d\x0a")
 
 func init() {
 	if CTX.Err() != nil {
@@ -267,6 +270,21 @@ func init() {
 		panic(err)
 	}
 
+	f, err = FS.OpenFile(CTX, "templates/search.html.tmpl", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = f.Write(FileTemplatesSearchHTMLTmpl)
+	if err != nil {
+		panic(err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		panic(err)
+	}
+
 	Handler = &webdav.Handler{
 		FileSystem: FS,
 		LockSystem: webdav.NewMemLS(),
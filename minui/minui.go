@@ -4,7 +4,6 @@ import (
 	"encoding/hex"
 	"html/template"
 	"io"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -21,6 +20,7 @@ import (
 	"github.com/u-speak/core/post"
 	"github.com/u-speak/core/tangle"
 	"github.com/u-speak/core/tangle/datastore"
+	"github.com/u-speak/core/tangle/query"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -34,6 +34,19 @@ type Server struct {
 	message string
 }
 
+type postView struct {
+	*post.Post
+	Confidence float64
+	Confirmed  bool
+}
+
+type searchView struct {
+	Query   string
+	Sort    string
+	Error   string
+	Results []tangle.Result
+}
+
 type renderer struct {
 	templates *template.Template
 }
@@ -91,6 +104,9 @@ var (
 		"Fingerprint": func(p *post.Post) string {
 			return hex.EncodeToString(p.Pubkey.PrimaryKey.Fingerprint[:])
 		},
+		"Percent": func(f float64) string {
+			return strconv.FormatFloat(f*100, 'f', 0, 64) + "%"
+		},
 	}
 )
 
@@ -142,25 +158,22 @@ func (s *Server) Run() {
 	e.GET("/", s.getIndex)
 	e.GET("/themes/:theme", s.switchTheme)
 	e.GET("/posts/:hash", s.getPost)
+	e.GET("/search", s.getSearch)
 	e.GET("/*", echo.WrapHandler(Handler))
 
 	log.Fatal(e.StartTLS(s.listen, s.sslcert, s.sslkey))
 }
 
 func (s *Server) getIndex(c echo.Context) error {
-	hs := s.node.Tangle.Hashes()
-	for i := range hs {
-		j := rand.Intn(i + 1)
-		hs[i], hs[j] = hs[j], hs[i]
+	hs, err := s.node.Tangle.Sample(10)
+	if err != nil {
+		log.Error(err)
 	}
 	sites := []*tangle.Object{}
-	l := 10
-	if l > len(hs) {
-		l = len(hs)
-	}
-	for _, h := range hs[:l] {
-		o := s.node.Tangle.Get(h)
-		if o == nil {
+	for _, h := range hs {
+		o, err := s.node.Tangle.Get(h)
+		if err != nil {
+			log.Error(err)
 			continue
 		}
 		sites = append(sites, o)
@@ -173,14 +186,18 @@ func (s *Server) getPost(c echo.Context) error {
 	if err != nil {
 		return s.error404(c)
 	}
-	o := s.node.Tangle.Get(h)
-	if o == nil {
+	o, err := s.node.Tangle.Get(h)
+	if err == tangle.ErrNotFound {
 		return s.error404(c)
 	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
 	if o.Site.Type != "post" {
 		return s.error404(c)
 	}
-	p := o.Data.(*post.Post)
+	p := &postView{Post: o.Data.(*post.Post), Confidence: s.node.Tangle.Confidence(h)}
+	p.Confirmed = p.Confidence >= s.node.Tangle.ConfirmationThreshold()
 	return c.Render(http.StatusOK, "templates/post.html.tmpl", response{
 		Theme:   c.Get("theme").(string),
 		Message: s.message,
@@ -189,6 +206,27 @@ func (s *Server) getPost(c echo.Context) error {
 	})
 }
 
+func (s *Server) getSearch(c echo.Context) error {
+	v := &searchView{Query: c.QueryParam("q"), Sort: c.QueryParam("sort")}
+	q, err := query.Parse(v.Query)
+	if err == nil {
+		var order query.Order
+		order, err = query.ParseOrder(v.Sort)
+		if err == nil {
+			v.Results = s.node.Tangle.Query(q, order)
+		}
+	}
+	if err != nil {
+		v.Error = err.Error()
+	}
+	return c.Render(http.StatusOK, "templates/search.html.tmpl", response{
+		Theme:   c.Get("theme").(string),
+		Message: s.message,
+		Data:    v,
+		Path:    "/search?q=" + url.QueryEscape(v.Query) + "&sort=" + url.QueryEscape(v.Sort),
+	})
+}
+
 func (s *Server) switchTheme(c echo.Context) error {
 	cookie := new(http.Cookie)
 	cookie.Name = "theme"
@@ -12,6 +12,7 @@ It has these top-level messages:
 	Void
 	Site
 	SuccessReturn
+	SiteRequest
 */
 package node
 
@@ -41,6 +42,8 @@ type Info struct {
 	ListenInterface string   `protobuf:"bytes,3,opt,name=ListenInterface" json:"ListenInterface,omitempty"`
 	Connections     []string `protobuf:"bytes,4,rep,name=Connections" json:"Connections,omitempty"`
 	Hashes          [][]byte `protobuf:"bytes,5,rep,name=Hashes,proto3" json:"Hashes,omitempty"`
+	Network         string   `protobuf:"bytes,6,opt,name=Network" json:"Network,omitempty"`
+	Genesis         [][]byte `protobuf:"bytes,7,rep,name=Genesis,proto3" json:"Genesis,omitempty"`
 }
 
 func (m *Info) Reset()                    { *m = Info{} }
@@ -83,6 +86,20 @@ func (m *Info) GetHashes() [][]byte {
 	return nil
 }
 
+func (m *Info) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *Info) GetGenesis() [][]byte {
+	if m != nil {
+		return m.Genesis
+	}
+	return nil
+}
+
 type Void struct {
 }
 
@@ -98,6 +115,9 @@ type Site struct {
 	Type      string   `protobuf:"bytes,4,opt,name=Type" json:"Type,omitempty"`
 	Data      []byte   `protobuf:"bytes,5,opt,name=Data,proto3" json:"Data,omitempty"`
 	Tip       bool     `protobuf:"varint,6,opt,name=Tip" json:"Tip,omitempty"`
+	Origin    string   `protobuf:"bytes,7,opt,name=Origin" json:"Origin,omitempty"`
+	PoW       string   `protobuf:"bytes,8,opt,name=PoW" json:"PoW,omitempty"`
+	Time      int64    `protobuf:"varint,9,opt,name=Time" json:"Time,omitempty"`
 }
 
 func (m *Site) Reset()                    { *m = Site{} }
@@ -147,6 +167,27 @@ func (m *Site) GetTip() bool {
 	return false
 }
 
+func (m *Site) GetOrigin() string {
+	if m != nil {
+		return m.Origin
+	}
+	return ""
+}
+
+func (m *Site) GetPoW() string {
+	if m != nil {
+		return m.PoW
+	}
+	return ""
+}
+
+func (m *Site) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
 type SuccessReturn struct {
 }
 
@@ -155,11 +196,28 @@ func (m *SuccessReturn) String() string            { return proto.CompactTextStr
 func (*SuccessReturn) ProtoMessage()               {}
 func (*SuccessReturn) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
+type SiteRequest struct {
+	Hash []byte `protobuf:"bytes,1,opt,name=Hash,proto3" json:"Hash,omitempty"`
+}
+
+func (m *SiteRequest) Reset()                    { *m = SiteRequest{} }
+func (m *SiteRequest) String() string            { return proto.CompactTextString(m) }
+func (*SiteRequest) ProtoMessage()               {}
+func (*SiteRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *SiteRequest) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Info)(nil), "Info")
 	proto.RegisterType((*Void)(nil), "Void")
 	proto.RegisterType((*Site)(nil), "Site")
 	proto.RegisterType((*SuccessReturn)(nil), "SuccessReturn")
+	proto.RegisterType((*SiteRequest)(nil), "SiteRequest")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -176,6 +234,7 @@ type DistributionServiceClient interface {
 	GetInfo(ctx context.Context, in *Info, opts ...grpc.CallOption) (*Info, error)
 	AddSite(ctx context.Context, in *Site, opts ...grpc.CallOption) (*SuccessReturn, error)
 	Splice(ctx context.Context, opts ...grpc.CallOption) (DistributionService_SpliceClient, error)
+	GetSite(ctx context.Context, in *SiteRequest, opts ...grpc.CallOption) (*Site, error)
 }
 
 type distributionServiceClient struct {
@@ -238,12 +297,22 @@ func (x *distributionServiceSpliceClient) CloseAndRecv() (*SuccessReturn, error)
 	return m, nil
 }
 
+func (c *distributionServiceClient) GetSite(ctx context.Context, in *SiteRequest, opts ...grpc.CallOption) (*Site, error) {
+	out := new(Site)
+	err := grpc.Invoke(ctx, "/DistributionService/GetSite", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for DistributionService service
 
 type DistributionServiceServer interface {
 	GetInfo(context.Context, *Info) (*Info, error)
 	AddSite(context.Context, *Site) (*SuccessReturn, error)
 	Splice(DistributionService_SpliceServer) error
+	GetSite(context.Context, *SiteRequest) (*Site, error)
 }
 
 func RegisterDistributionServiceServer(s *grpc.Server, srv DistributionServiceServer) {
@@ -312,6 +381,24 @@ func (x *distributionServiceSpliceServer) Recv() (*Site, error) {
 	return m, nil
 }
 
+func _DistributionService_GetSite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SiteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DistributionServiceServer).GetSite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/DistributionService/GetSite",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DistributionServiceServer).GetSite(ctx, req.(*SiteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _DistributionService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "DistributionService",
 	HandlerType: (*DistributionServiceServer)(nil),
@@ -324,6 +411,10 @@ var _DistributionService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "AddSite",
 			Handler:    _DistributionService_AddSite_Handler,
 		},
+		{
+			MethodName: "GetSite",
+			Handler:    _DistributionService_GetSite_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -338,26 +429,31 @@ var _DistributionService_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("node.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 322 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x51, 0xcb, 0x6a, 0xeb, 0x30,
-	0x14, 0x8c, 0xae, 0x65, 0xe7, 0xe6, 0xdc, 0xdc, 0xa6, 0xa8, 0xa5, 0xa8, 0xa1, 0x0b, 0xa3, 0x6e,
-	0xb4, 0xf2, 0xa2, 0xfd, 0x82, 0x92, 0x40, 0x1b, 0x08, 0x5d, 0xc8, 0x21, 0x7b, 0xc7, 0x3e, 0x69,
-	0x04, 0x41, 0x32, 0x96, 0x52, 0xc8, 0x4f, 0xf4, 0x0b, 0xfa, 0xb1, 0x45, 0x4a, 0x42, 0x1f, 0xd0,
-	0xd5, 0x99, 0x19, 0x46, 0x47, 0x33, 0x12, 0x80, 0xb1, 0x0d, 0x16, 0x6d, 0x67, 0xbd, 0x15, 0xef,
-	0x04, 0xe8, 0xcc, 0xac, 0x2d, 0xe3, 0xd0, 0x5f, 0x62, 0xe7, 0xb4, 0x35, 0x9c, 0xe4, 0x44, 0x0e,
-	0xd4, 0x89, 0xb2, 0x2b, 0xc8, 0xe6, 0x68, 0x5e, 0xfc, 0x86, 0xff, 0xc9, 0x89, 0xa4, 0xea, 0xc8,
-	0x98, 0x84, 0xd1, 0x5c, 0x3b, 0x8f, 0x66, 0x66, 0x3c, 0x76, 0xeb, 0xaa, 0x46, 0x9e, 0xc4, 0x93,
-	0x3f, 0x65, 0x96, 0xc3, 0xbf, 0x89, 0x35, 0x06, 0x6b, 0xaf, 0xad, 0x71, 0x9c, 0xe6, 0x89, 0x1c,
-	0xa8, 0xaf, 0x52, 0xb8, 0xe3, 0xa9, 0x72, 0x1b, 0x74, 0x3c, 0xcd, 0x13, 0x39, 0x54, 0x47, 0x26,
-	0x32, 0xa0, 0x4b, 0xab, 0x1b, 0xf1, 0x46, 0x80, 0x96, 0xda, 0x23, 0xbb, 0x81, 0xc1, 0xb2, 0xda,
-	0xea, 0xa6, 0xf2, 0xe8, 0x38, 0x89, 0xde, 0x4f, 0x81, 0x5d, 0x42, 0xfa, 0x6c, 0x4d, 0x8d, 0xc7,
-	0xa4, 0x07, 0x12, 0xaa, 0x4d, 0xac, 0xf1, 0x68, 0x7c, 0x0c, 0x38, 0x54, 0x27, 0xca, 0x18, 0xd0,
-	0xc5, 0xbe, 0x45, 0x4e, 0x63, 0xee, 0x88, 0x83, 0x36, 0xad, 0x7c, 0xc5, 0xd3, 0x68, 0x8d, 0x98,
-	0x9d, 0x43, 0xb2, 0xd0, 0x2d, 0xcf, 0x72, 0x22, 0xff, 0xaa, 0x00, 0xc5, 0x08, 0xfe, 0x97, 0xbb,
-	0xba, 0x46, 0xe7, 0x14, 0xfa, 0x5d, 0x67, 0xee, 0xf6, 0x70, 0x31, 0xd5, 0xce, 0x77, 0x7a, 0xb5,
-	0x0b, 0x95, 0x4a, 0xec, 0x5e, 0x75, 0x8d, 0xec, 0x1a, 0xfa, 0x8f, 0xe8, 0xe3, 0x0b, 0xa7, 0x45,
-	0x18, 0xe3, 0xc3, 0x10, 0x3d, 0x26, 0xa0, 0xff, 0xd0, 0x34, 0xb1, 0x55, 0x5a, 0x84, 0x31, 0x3e,
-	0x2b, 0xbe, 0xed, 0x14, 0x3d, 0x76, 0x0b, 0x59, 0xd9, 0x6e, 0xc3, 0xa2, 0xdf, 0x2c, 0x92, 0xac,
-	0xb2, 0xf8, 0x95, 0xf7, 0x1f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x14, 0x70, 0x01, 0x73, 0xd8, 0x01,
-	0x00, 0x00,
+	// 403 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x52, 0xcb, 0x6e, 0xd5, 0x30,
+	0x10, 0xbd, 0x26, 0xaf, 0x66, 0x1a, 0x28, 0x32, 0x08, 0x99, 0x2b, 0x16, 0xc1, 0x6c, 0xb2, 0xca,
+	0x02, 0xbe, 0x00, 0xb5, 0x12, 0x54, 0xaa, 0x0a, 0x72, 0xaa, 0xcb, 0x3a, 0x4d, 0xa6, 0xad, 0x45,
+	0xb1, 0x83, 0xed, 0x80, 0xf8, 0x16, 0x7e, 0x89, 0x05, 0x9f, 0x84, 0x3c, 0xc9, 0x15, 0x05, 0xa9,
+	0xab, 0x39, 0xe7, 0x64, 0xc6, 0x73, 0x66, 0x26, 0x00, 0xc6, 0x8e, 0xd8, 0x4e, 0xce, 0x06, 0x2b,
+	0x7f, 0x33, 0x48, 0x4f, 0xcd, 0x95, 0xe5, 0x02, 0x8a, 0x1d, 0x3a, 0xaf, 0xad, 0x11, 0xac, 0x66,
+	0x4d, 0xa9, 0xf6, 0x94, 0x3f, 0x83, 0xfc, 0x0c, 0xcd, 0x75, 0xb8, 0x11, 0x0f, 0x6a, 0xd6, 0xa4,
+	0x6a, 0x65, 0xbc, 0x81, 0xa3, 0x33, 0xed, 0x03, 0x9a, 0x53, 0x13, 0xd0, 0x5d, 0xf5, 0x03, 0x8a,
+	0x84, 0x2a, 0xff, 0x97, 0x79, 0x0d, 0x87, 0xc7, 0xd6, 0x18, 0x1c, 0x82, 0xb6, 0xc6, 0x8b, 0xb4,
+	0x4e, 0x9a, 0x52, 0xdd, 0x95, 0x62, 0x8f, 0xf7, 0xbd, 0xbf, 0x41, 0x2f, 0xb2, 0x3a, 0x69, 0x2a,
+	0xb5, 0xb2, 0xe8, 0xea, 0x1c, 0xc3, 0x77, 0xeb, 0x3e, 0x8b, 0x7c, 0x71, 0xb5, 0xd2, 0xf8, 0xe5,
+	0x1d, 0x1a, 0xf4, 0xda, 0x8b, 0x82, 0x4a, 0xf6, 0x54, 0xe6, 0x90, 0xee, 0xac, 0x1e, 0xe5, 0x2f,
+	0x06, 0x69, 0xa7, 0x03, 0xf2, 0x17, 0x50, 0xee, 0xfa, 0x5b, 0x3d, 0xf6, 0x01, 0xbd, 0x60, 0x94,
+	0xfc, 0x57, 0xe0, 0x4f, 0x21, 0x3b, 0xb7, 0x66, 0xc0, 0x75, 0xba, 0x85, 0xc4, 0xe7, 0x8f, 0xad,
+	0x09, 0x68, 0x02, 0x0d, 0x55, 0xa9, 0x3d, 0xe5, 0x1c, 0xd2, 0x8b, 0x1f, 0x13, 0x8a, 0x94, 0xfc,
+	0x10, 0x8e, 0xda, 0x49, 0x1f, 0x7a, 0x91, 0x51, 0x2a, 0x61, 0xfe, 0x18, 0x92, 0x0b, 0x3d, 0x91,
+	0xed, 0x03, 0x15, 0x61, 0x1c, 0xf2, 0x83, 0xd3, 0xd7, 0xda, 0x88, 0x82, 0x6a, 0x57, 0x16, 0x33,
+	0x3f, 0xda, 0x4f, 0xe2, 0x80, 0xc4, 0x08, 0xa9, 0x87, 0xfe, 0x82, 0xa2, 0xac, 0x59, 0x93, 0x28,
+	0xc2, 0xf2, 0x08, 0x1e, 0x76, 0xf3, 0x30, 0xa0, 0xf7, 0x0a, 0xc3, 0xec, 0x8c, 0x7c, 0x09, 0x87,
+	0x71, 0x3c, 0x85, 0x5f, 0x67, 0xf4, 0xe4, 0x2b, 0x2e, 0x8d, 0xae, 0x57, 0x29, 0xc2, 0xaf, 0x7f,
+	0x32, 0x78, 0x72, 0xa2, 0x7d, 0x70, 0xfa, 0x72, 0x8e, 0x8b, 0xee, 0xd0, 0x7d, 0xd3, 0x03, 0xf2,
+	0xe7, 0x71, 0x79, 0x81, 0xee, 0x9e, 0xb5, 0x31, 0x6c, 0x97, 0x20, 0x37, 0x5c, 0x42, 0xf1, 0x76,
+	0x1c, 0x69, 0x6f, 0x59, 0x1b, 0xc3, 0xf6, 0x51, 0xfb, 0x6f, 0xdf, 0x0d, 0x7f, 0x05, 0x79, 0x37,
+	0xdd, 0xea, 0xe1, 0xfe, 0x94, 0x86, 0xf1, 0x9a, 0x7a, 0xd0, 0x43, 0x55, 0x7b, 0xc7, 0xe8, 0x76,
+	0xa9, 0x91, 0x9b, 0xcb, 0x9c, 0x7e, 0xc1, 0x37, 0x7f, 0x06, 0x00, 0x84, 0x37, 0x9a, 0xf4, 0x90,
+	0x02, 0x00, 0x00,
 }
@@ -8,7 +8,7 @@ import (
 func FromObject(o *tangle.Object) (*Site, error) {
 	vs := [][]byte{}
 	for _, v := range o.Site.Validates {
-		vs = append(vs, v.Hash().Slice())
+		vs = append(vs, v.Slice())
 	}
 	data, err := o.Data.Serialize()
 	if err != nil {
@@ -20,5 +20,7 @@ func FromObject(o *tangle.Object) (*Site, error) {
 		Content:   o.Site.Content.Slice(),
 		Type:      o.Site.Type,
 		Data:      data,
+		PoW:       o.Site.PoW,
+		Time:      o.Site.Time,
 	}, nil
 }
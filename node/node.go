@@ -9,15 +9,22 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/u-speak/core/config"
-	"github.com/u-speak/core/img"
-	"github.com/u-speak/core/post"
+	// Registers the image payload type
+	_ "github.com/u-speak/core/img"
+	// Registers the post payload type
+	_ "github.com/u-speak/core/post"
 	"github.com/u-speak/core/tangle"
 	"github.com/u-speak/core/tangle/datastore"
 	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/index"
+	"github.com/u-speak/core/tangle/orphan"
 	"github.com/u-speak/core/tangle/site"
 	"github.com/u-speak/core/tangle/store"
+	"github.com/u-speak/core/tangle/store/badgerstore"
 	"github.com/u-speak/core/tangle/store/boltstore"
 
 	"github.com/jasonlvhit/gocron"
@@ -32,6 +39,13 @@ const (
 	MaxMsgSize = 5242880
 )
 
+var (
+	// ErrNetworkMismatch is returned when a peer belongs to a different network or uses a different genesis
+	ErrNetworkMismatch = errors.New("Peer belongs to a different network")
+	// ErrUnknownEngine is returned by OpenStore for storage engines other than "bolt" and "badger"
+	ErrUnknownEngine = errors.New("Unknown storage engine")
+)
+
 // Node is a wrapper around the chain. Nodes are the backbone of the network
 type Node struct {
 	Tangle           *tangle.Tangle
@@ -42,12 +56,16 @@ type Node struct {
 	Hooks            struct {
 		PreAdd string
 	}
+	orphans *orphan.Pool
+	// receiveMu serializes adding received sites and solidifying orphans
+	receiveMu sync.Mutex
 }
 
 // Status is used for reporting this nodes configuration to other nodes
 type Status struct {
 	Address        string      `json:"address"`
 	Version        string      `json:"version"`
+	Network        string      `json:"network"`
 	Length         uint64      `json:"length"`
 	Connections    []string    `json:"connections"`
 	Recomendations []string    `json:"recomendations"`
@@ -70,13 +88,69 @@ func New(c config.Configuration) (*Node, error) {
 		Hooks:            c.Hooks,
 		APIAddr:          c.Web.API.PublicEndpoint,
 	}
-	bs, err := boltstore.New(store.Options{Path: c.Storage.TanglePath})
+	bs, err := OpenStore(c)
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := time.ParseDuration(c.Tangle.Orphans.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+	n.orphans, err = orphan.New(orphan.Options{
+		Path:    c.Storage.OrphanPath,
+		MaxSize: c.Tangle.Orphans.MaxSize,
+		MaxAge:  maxAge,
+	})
+	if err != nil {
+		return nil, err
+	}
+	idx, err := index.NewBoltIndex(c.Storage.IndexPath)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := time.ParseDuration(c.Tangle.Confirmation.Interval)
+	if err != nil {
+		return nil, err
+	}
+	ts := c.Tangle.TipSelection
+	sel, err := tangle.NewTipSelector(ts.Strategy, ts.Alpha, ts.Depth)
+	if err != nil {
+		return nil, err
+	}
+	tngl, err := tangle.New(tangle.Options{
+		Store:                 bs,
+		DataPath:              c.Storage.DataPath,
+		TipSelector:           sel,
+		ConfidenceSamples:     c.Tangle.Confirmation.Samples,
+		ConfirmationThreshold: c.Tangle.Confirmation.Threshold,
+		ConfirmationInterval:  interval,
+		Index:                 idx,
+		Network:               c.Network.ID,
+		Genesis:               tangle.NewGenesis(c.Network.Genesis),
+		Difficulty:            c.Network.Difficulty,
+		PoW:                   c.Network.PoW,
+	})
 	if err != nil {
+		bs.Close()
+		idx.Close()
+		n.orphans.Close()
 		return nil, err
 	}
-	tngl, err := tangle.New(tangle.Options{Store: bs, DataPath: c.Storage.DataPath})
 	n.Tangle = tngl
-	return n, err
+	return n, nil
+}
+
+// OpenStore opens the site store at the tangle path using the configured engine, either "bolt" or "badger".
+// Badger stores are directories
+func OpenStore(c config.Configuration) (store.Store, error) {
+	o := store.Options{Path: c.Storage.TanglePath}
+	switch c.Storage.Engine {
+	case "bolt", "":
+		return boltstore.New(o)
+	case "badger":
+		return badgerstore.New(o)
+	}
+	return nil, ErrUnknownEngine
 }
 
 // Status returns the current running configuration of the node
@@ -94,7 +168,7 @@ func (n *Node) Status() Status {
 		Length:         uint64(n.Tangle.Size()),
 		Connections:    cons,
 		Version:        n.Version,
-		Hashes:         n.Tangle.Hashes(),
+		Network:        n.Tangle.Network(),
 		Recomendations: recs,
 	}
 }
@@ -111,13 +185,32 @@ func (n *Node) RemoteStatus(s string) (*Status, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !n.sameNetwork(i) {
+		return nil, ErrNetworkMismatch
+	}
 	hs := []hash.Hash{}
-	for _, h := range i.Hashes {
-		hs = append(hs, hash.FromSlice(h))
+	remote := make(map[hash.Hash]bool, len(i.Hashes))
+	a, d := []hash.Hash{}, []hash.Hash{}
+	for _, b := range i.Hashes {
+		h := hash.FromSlice(b)
+		hs = append(hs, h)
+		remote[h] = true
+		if !n.Tangle.Has(h) {
+			a = append(a, h)
+		}
+	}
+	err = n.Tangle.ForEach(func(h hash.Hash, _ *site.Site) bool {
+		if !remote[h] {
+			d = append(d, h)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
-	a, d := hash.Diff(n.Tangle.Hashes(), hs)
 	return &Status{
 		Version:     i.Version,
+		Network:     i.Network,
 		Length:      i.Length,
 		Connections: i.Connections,
 		Address:     i.ListenInterface,
@@ -128,26 +221,53 @@ func (n *Node) RemoteStatus(s string) (*Status, error) {
 
 // Info returns the serializable info struct
 func (n *Node) Info() *d.Info {
-	s := n.Status()
 	cons := []string{}
 	for k := range n.remoteInterfaces {
 		cons = append(cons, k)
 	}
 	hs := [][]byte{}
-	for _, h := range n.Tangle.Hashes() {
-		hs = append(hs, h.Slice())
+	err := n.Tangle.ForEach(func(h hash.Hash, _ *site.Site) bool {
+		hs = append(hs, append([]byte{}, h.Slice()...))
+		return true
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	gen := [][]byte{}
+	for _, h := range n.Tangle.Genesis() {
+		gen = append(gen, h.Slice())
 	}
 	return &d.Info{
-		Length:          s.Length,
-		ListenInterface: s.Address,
+		Length:          uint64(len(hs)),
+		ListenInterface: n.ListenInterface,
 		Version:         n.Version,
 		Connections:     cons,
 		Hashes:          hs,
+		Network:         n.Tangle.Network(),
+		Genesis:         gen,
 	}
 }
 
-// GetInfo is a all purpose status request
+// sameNetwork checks whether the peer uses the network ID and genesis sites of this node
+func (n *Node) sameNetwork(i *d.Info) bool {
+	gen := n.Tangle.Genesis()
+	if i.Network != n.Tangle.Network() || len(i.Genesis) != len(gen) {
+		return false
+	}
+	for k, h := range gen {
+		if hash.FromSlice(i.Genesis[k]) != h {
+			return false
+		}
+	}
+	return true
+}
+
+// GetInfo is a all purpose status request. Peers from a different network are refused
 func (n *Node) GetInfo(ctx context.Context, r *d.Info) (*d.Info, error) {
+	if !n.sameNetwork(r) {
+		log.Warnf("Refusing %s from network %q", r.ListenInterface, r.Network)
+		return nil, ErrNetworkMismatch
+	}
 	if _, ok := n.remoteInterfaces[r.ListenInterface]; !ok && n.ListenInterface != r.ListenInterface {
 		log.Infof("Establishing reverse connection with %s", r.ListenInterface)
 		n.Connect(r.ListenInterface)
@@ -188,6 +308,16 @@ func (n *Node) startCron() {
 			}
 		}
 	})
+	gocron.Every(1).Minute().Do(func() {
+		c, err := n.orphans.Expire(time.Now())
+		if err != nil {
+			log.Error(err)
+		}
+		if c > 0 {
+			log.Infof("Dropped %d expired orphans", c)
+		}
+		n.requestMissing()
+	})
 	<-gocron.Start()
 }
 
@@ -202,11 +332,15 @@ func (n *Node) connect(remote string) error {
 	}
 	defer conn.Close()
 	client := d.NewDistributionServiceClient(conn)
-	_, err = client.GetInfo(context.Background(), n.Info())
+	i, err := client.GetInfo(context.Background(), n.Info())
 	if err != nil {
 		delete(n.remoteInterfaces, remote)
 		return err
 	}
+	if !n.sameNetwork(i) {
+		delete(n.remoteInterfaces, remote)
+		return ErrNetworkMismatch
+	}
 	n.remoteInterfaces[remote] = struct{}{}
 	log.Infof("Added connection %s", remote)
 	return nil
@@ -234,18 +368,41 @@ func (n *Node) Connect(r string) error {
 	return nil
 }
 
-// Submit is called whenever a new site is submitted to the network
+// Submit is called whenever a new site is submitted to the network.
+// Unlike received sites, local sites have to validate a current tip, so they are added with Tangle.Add before they are pushed
 func (n *Node) Submit(o *tangle.Object) error {
+	err := n.add(o)
+	if err != nil {
+		return err
+	}
 	log.Infof("Pushing site %s to network", o.Site.Hash())
 	return n.Push(o)
 }
 
+// add adds a local site and injects the orphans waiting for it
+func (n *Node) add(o *tangle.Object) error {
+	n.receiveMu.Lock()
+	defer n.receiveMu.Unlock()
+	h := o.Site.Hash()
+	if n.Tangle.Has(h) {
+		return nil
+	}
+	err := n.Tangle.Add(o)
+	if err != nil {
+		return err
+	}
+	log.Infof("Successfully added site: %s", h)
+	n.solidify(h)
+	return nil
+}
+
 // Push sends a site to all connected nodes
 func (n *Node) Push(o *tangle.Object) error {
 	ds, err := d.FromObject(o)
 	if err != nil {
 		return err
 	}
+	ds.Origin = n.ListenInterface
 	for r := range n.remoteInterfaces {
 		conn, err := dial(r)
 		if err != nil {
@@ -285,15 +442,31 @@ func (n *Node) AddSite(ctx context.Context, s *d.Site) (*d.SuccessReturn, error)
 			log.Errorf("Error running PreAdd hook: %s", err.Error())
 		}
 	}
-	err = n.Tangle.Inject(o, true)
+	err = n.receive(o, true, s.Origin, true, false)
 	if err != nil {
 		log.Errorf("Failed to add site: %s", err)
-	} else {
-		log.Infof("Successfully added site: %s", o.Site.Hash())
 	}
 	return &d.SuccessReturn{}, err
 }
 
+// GetSite sends a single site to a node missing it
+func (n *Node) GetSite(ctx context.Context, r *d.SiteRequest) (*d.Site, error) {
+	h := hash.FromSlice(r.Hash)
+	o, err := n.Tangle.Get(h)
+	if err == tangle.ErrNotFound {
+		return nil, errors.New("This node does not know about hash " + h.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	ds, err := d.FromObject(o)
+	if err != nil {
+		return nil, err
+	}
+	ds.Origin = n.ListenInterface
+	return ds, nil
+}
+
 // Merge requests to merge with a remote
 func (n *Node) Merge(r string) error {
 	s, err := n.RemoteStatus(r)
@@ -315,8 +488,9 @@ func (n *Node) Merge(r string) error {
 		return err
 	}
 	for _, h := range s.HashDiff.Deletions {
-		o := n.Tangle.Get(h)
-		if o == nil {
+		o, err := n.Tangle.Get(h)
+		if err != nil {
+			log.Errorf("Not sending site %s: %s", h, err)
 			continue
 		}
 		do, err := d.FromObject(o)
@@ -326,6 +500,7 @@ func (n *Node) Merge(r string) error {
 		if n.Tangle.HasTip(o.Site.Hash()) {
 			do.Tip = true
 		}
+		do.Origin = n.ListenInterface
 		err = stream.Send(do)
 		if err != nil {
 			return err
@@ -339,103 +514,220 @@ func (n *Node) Merge(r string) error {
 	return err
 }
 
-// Splice injects the recieved sites into the tangle
+// Splice injects the recieved sites into the tangle.
+// Sites arriving before their parents are kept in the orphan pool.
 func (n *Node) Splice(stream d.DistributionService_SpliceServer) error {
-	canLink := func(o *d.Site) bool {
-		for _, s := range o.Validates {
-			h := hash.FromSlice(s)
-			if n.Tangle.Get(h) == nil {
-				return false
-			}
+	log.Info("Starting Splice")
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			break
 		}
-		return true
-	}
-	inj := func(o *d.Site) error {
-		s, err := n.toObject(o)
 		if err != nil {
+			log.Error(err)
 			return err
 		}
-		log.Infof("Received Site %s", s.Site.Hash())
-		err = n.Tangle.Inject(s, o.Tip)
+		o, err := n.toObject(in)
 		if err != nil {
 			log.Error(err)
-			return err
+			continue
 		}
+		log.Infof("Received Site %s", o.Site.Hash())
+		err = n.receive(o, in.Tip, in.Origin, false, true)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+	log.Infof("Finished Splicing, %d orphans pending", n.orphans.Size())
+	go n.requestMissing()
+	return nil
+}
+
+// receive adds the site to the tangle, or keeps it in the orphan pool until its parents are known.
+// Missing parents are requested from origin if fetch is set.
+// Sites received through Splice are history, which may contain sites without time.
+func (n *Node) receive(o *tangle.Object, tip bool, origin string, fetch bool, history bool) error {
+	n.receiveMu.Lock()
+	defer n.receiveMu.Unlock()
+	h := o.Site.Hash()
+	if n.Tangle.Has(h) || n.orphans.Has(h) {
 		return nil
 	}
-	log.Info("Starting Splice")
-	buff := make(map[*d.Site]bool)
-	for {
-		in, err := stream.Recv()
-		if err == io.EOF {
-			log.Info("Finished Splicing")
-			break
-		}
+	missing := n.missing(o.Site)
+	if len(missing) == 0 {
+		err := n.inject(o, tip, history)
 		if err != nil {
-			log.Error(err)
 			return err
 		}
-		if canLink(in) {
-			err := inj(in)
+		log.Infof("Successfully added site: %s", h)
+		n.solidify(h)
+		return nil
+	}
+	err := n.Tangle.Verify(o, history)
+	if err != nil {
+		return err
+	}
+	data, err := o.Data.Serialize()
+	if err != nil {
+		return err
+	}
+	err = n.orphans.Add(&orphan.Orphan{Site: o.Site, Data: data, Tip: tip, History: history, Origin: origin, Received: time.Now()})
+	if err != nil {
+		return err
+	}
+	log.Infof("Site %s is missing %d parents, keeping it as orphan", h, len(missing))
+	if fetch {
+		go n.fetch(origin, missing, history)
+	}
+	return nil
+}
+
+// solidify injects all orphans which became complete by adding h. The caller must hold receiveMu
+func (n *Node) solidify(h hash.Hash) {
+	bound := []hash.Hash{h}
+	for len(bound) > 0 {
+		p := bound[len(bound)-1]
+		bound = bound[:len(bound)-1]
+		for _, c := range n.orphans.Waiting(p) {
+			o, err := n.orphans.Get(c)
 			if err != nil {
 				log.Error(err)
-				return err
+				continue
 			}
-		} else {
-			buff[in] = true
+			if len(n.missing(o.Site)) > 0 {
+				continue
+			}
+			err = n.orphans.Remove(c)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			obj, err := fromOrphan(o)
+			if err == nil {
+				err = n.inject(obj, o.Tip, o.History)
+			}
+			if err != nil {
+				log.Errorf("Dropping orphan %s: %s", c, err)
+				continue
+			}
+			log.Infof("Solidified site %s", c)
+			bound = append(bound, c)
 		}
 	}
-	log.Infof("Remaining injections: %d", len(buff))
-	for len(buff) > 0 {
-		origlen := len(buff)
-		for s := range buff {
-			if canLink(s) {
-				err := inj(s)
-				if err != nil {
-					log.Error(err)
-					return err
-				}
-				delete(buff, s)
+}
+
+// inject adds a received site, restoring it if it belongs to history
+func (n *Node) inject(o *tangle.Object, tip bool, history bool) error {
+	if history {
+		return n.Tangle.Restore(o, tip)
+	}
+	return n.Tangle.Inject(o, tip)
+}
+
+// missing returns the parents of s not known to the tangle
+func (n *Node) missing(s *site.Site) []hash.Hash {
+	hs := []hash.Hash{}
+	for _, v := range s.Validates {
+		if !n.Tangle.Has(v) {
+			hs = append(hs, v)
+		}
+	}
+	return hs
+}
+
+// requestMissing fetches the missing parents of all pending orphans from the nodes that sent them
+func (n *Node) requestMissing() {
+	type source struct {
+		origin  string
+		history bool
+	}
+	wanted := make(map[source][]hash.Hash)
+	seen := make(map[hash.Hash]bool)
+	for _, h := range n.orphans.Orphans() {
+		o, err := n.orphans.Get(h)
+		if err != nil {
+			continue
+		}
+		for _, m := range n.missing(o.Site) {
+			if seen[m] || n.orphans.Has(m) {
+				continue
 			}
+			seen[m] = true
+			src := source{origin: o.Origin, history: o.History}
+			wanted[src] = append(wanted[src], m)
 		}
-		if len(buff) == origlen {
-			return errors.New("Merge Failed! Invalid tangle structure")
+	}
+	for src, hs := range wanted {
+		n.fetch(src.origin, hs, src.history)
+	}
+}
+
+// fetch requests the specified sites from origin. The parents of history are history as well
+func (n *Node) fetch(origin string, hs []hash.Hash, history bool) {
+	if origin == "" {
+		log.Debugf("Not fetching %d sites, origin unknown", len(hs))
+		return
+	}
+	conn, err := dial(origin)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	defer conn.Close()
+	client := d.NewDistributionServiceClient(conn)
+	for _, h := range hs {
+		s, err := client.GetSite(context.Background(), &d.SiteRequest{Hash: h.Slice()})
+		if err != nil {
+			log.Errorf("Could not fetch %s from %s: %s", h, origin, err)
+			continue
+		}
+		o, err := n.toObject(s)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		if o.Site.Hash() != h {
+			log.Errorf("Node %s sent %s instead of %s", origin, o.Site.Hash(), h)
+			continue
+		}
+		err = n.receive(o, false, origin, true, history)
+		if err != nil {
+			log.Errorf("Failed to add site: %s", err)
 		}
 	}
-	return nil
 }
 
 func (n *Node) toObject(s *d.Site) (*tangle.Object, error) {
-	vs := []*site.Site{}
-	for _, h := range s.Validates {
-		o := n.Tangle.Get(hash.FromSlice(h))
-		if o == nil {
-			return nil, errors.New("This node does not know about hash " + hash.FromSlice(h).String())
-		}
-		vs = append(vs, o.Site)
-	}
-	var d datastore.Serializable
-	switch s.Type {
-	case "post":
-		d = &post.Post{}
-	case "image":
-		d = &img.Image{}
-	default:
+	vs := []hash.Hash{}
+	for _, b := range s.Validates {
+		vs = append(vs, hash.FromSlice(b))
+	}
+	return newObject(&site.Site{
+		Validates: vs,
+		Nonce:     s.Nonce,
+		Content:   hash.FromSlice(s.Content),
+		Type:      s.Type,
+		PoW:       s.PoW,
+		Time:      s.Time,
+	}, s.Data)
+}
+
+func fromOrphan(o *orphan.Orphan) (*tangle.Object, error) {
+	return newObject(o.Site, o.Data)
+}
+
+// newObject deserializes the payload of a public site type
+func newObject(s *site.Site, data []byte) (*tangle.Object, error) {
+	t, err := datastore.LookupPublic(s.Type)
+	if err != nil {
 		return nil, errors.New("Invalid site type")
 	}
-	err := d.Deserialize(s.Data)
+	d := t.New()
+	err = d.Deserialize(data)
 	if err != nil {
 		return nil, err
 	}
-	return &tangle.Object{
-		Site: &site.Site{
-			Validates: vs,
-			Nonce:     s.Nonce,
-			Content:   hash.FromSlice(s.Content),
-			Type:      s.Type,
-		},
-		Data: d,
-	}, nil
+	return &tangle.Object{Site: s, Data: d}, nil
 }
 
 func dial(r string) (*grpc.ClientConn, error) {
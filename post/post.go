@@ -4,12 +4,16 @@ package post
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/u-speak/core/tangle/datastore"
 	"github.com/u-speak/core/tangle/hash"
 
+	"github.com/gernest/front"
 	"golang.org/x/crypto/openpgp"
 	"golang.org/x/crypto/openpgp/armor"
 	"golang.org/x/crypto/openpgp/packet"
@@ -24,6 +28,15 @@ type Post struct {
 	Timestamp int64           `json:"date"`
 }
 
+func init() {
+	datastore.Register(datastore.Type{
+		Name:     "post",
+		New:      func() datastore.Serializable { return &Post{} },
+		Validate: validate,
+		Public:   true,
+	})
+}
+
 type serializable interface {
 	Serialize(w io.Writer) error
 }
@@ -41,6 +54,16 @@ func (p *Post) Verify() (*openpgp.Entity, error) {
 	return openpgp.CheckArmoredDetachedSignature(kr, strings.NewReader(p.Content), strings.NewReader(p.Signature))
 }
 
+func validate(s datastore.Serializable) error {
+	p := s.(*Post)
+	err := p.ReInit()
+	if err != nil {
+		return err
+	}
+	_, err = p.Verify()
+	return err
+}
+
 // Serialize implements tangle/datastore.serializable
 func (p *Post) Serialize() ([]byte, error) {
 	err := p.storePGPStr()
@@ -89,6 +112,52 @@ func (p *Post) Type() string {
 	return "post"
 }
 
+// Author implements tangle/datastore.Authored and returns the fingerprint of the signing key
+func (p *Post) Author() string {
+	if p.Pubkey == nil {
+		return ""
+	}
+	return fmt.Sprintf("%X", p.Pubkey.PrimaryKey.Fingerprint)
+}
+
+// Text implements tangle/datastore.Indexable
+func (p *Post) Text() string {
+	return p.Content
+}
+
+// Date implements tangle/datastore.Dated
+func (p *Post) Date() time.Time {
+	if p.Timestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(p.Timestamp, 0)
+}
+
+// Meta implements tangle/datastore.Described and returns the fields of the YAML frontmatter.
+// Lists like tags are flattened into multiple values.
+func (p *Post) Meta() map[string][]string {
+	m := front.NewMatter()
+	m.Handle("---", front.YAMLHandler)
+	f, _, err := m.Parse(strings.NewReader(p.Content))
+	meta := make(map[string][]string)
+	if err != nil {
+		return meta
+	}
+	for k, v := range f {
+		k = strings.ToLower(k)
+		switch vs := v.(type) {
+		case []interface{}:
+			for _, e := range vs {
+				meta[k] = append(meta[k], fmt.Sprint(e))
+			}
+		case nil:
+		default:
+			meta[k] = append(meta[k], fmt.Sprint(vs))
+		}
+	}
+	return meta
+}
+
 func asciiDecodeEntity(s string) (*openpgp.Entity, error) {
 	buff := strings.NewReader(s)
 	block, err := armor.Decode(buff)
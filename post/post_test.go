@@ -86,3 +86,20 @@ bATkpmKdxC/qCgICRaXugOdehrLpoEEMSrHlc3xG3bmSvNa0U7Y=
 	assert.NoError(t, err)
 	t.Log(enc)
 }
+
+func TestAuthor(t *testing.T) {
+	p := post(t)
+	assert.Len(t, p.Author(), 40)
+	assert.Equal(t, strings.ToUpper(p.Author()), p.Author())
+	assert.Equal(t, "", (&Post{}).Author())
+}
+
+func TestMeta(t *testing.T) {
+	p := &Post{Content: "---\ntitle: Hello World\ntags: [news, Go]\n---\nBody", Timestamp: 1500000000}
+	m := p.Meta()
+	assert.Equal(t, []string{"Hello World"}, m["title"])
+	assert.Equal(t, []string{"news", "Go"}, m["tags"])
+	assert.Equal(t, int64(1500000000), p.Date().Unix())
+	assert.Empty(t, (&Post{Content: "No frontmatter"}).Meta())
+	assert.True(t, (&Post{}).Date().IsZero())
+}
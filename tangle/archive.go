@@ -0,0 +1,257 @@
+package tangle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/u-speak/core/tangle/datastore"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+
+	"github.com/vmihailenco/msgpack"
+	"golang.org/x/crypto/blake2b"
+)
+
+// An archive starts with ArchiveMagic and the ArchiveVersion byte, followed by the records.
+// Each record is prefixed with its length as uvarint. A zero length ends the records and is followed
+// by the number of records as big endian uint64 and the blake2b-256 checksum of all preceding bytes.
+const (
+	// ArchiveMagic identifies tangle archives
+	ArchiveMagic = "USPKTNGL"
+	// ArchiveVersion is the version of the archive format written by Export
+	ArchiveVersion = 1
+	// MaxArchiveRecord is the largest accepted record
+	MaxArchiveRecord = 16 << 20
+)
+
+var (
+	// ErrInvalidArchive is returned when the input is not a tangle archive or truncated
+	ErrInvalidArchive = errors.New("Invalid tangle archive")
+	// ErrArchiveVersion is returned for archives written in an unsupported format version
+	ErrArchiveVersion = errors.New("Unsupported tangle archive version")
+	// ErrArchiveChecksum is returned when the checksum trailer does not match the archive
+	ErrArchiveChecksum = errors.New("Tangle archive checksum mismatch")
+)
+
+type archiveRecord struct {
+	Site []byte
+	Data []byte
+	Tip  bool
+}
+
+// Export writes all sites to w, parents before the sites validating them.
+// Virtual sites like the genesis are implied and not exported. It returns the number of written sites.
+func (t *Tangle) Export(w io.Writer) (int, error) {
+	bw := bufio.NewWriter(w)
+	sum, _ := blake2b.New256(nil)
+	out := io.MultiWriter(bw, sum)
+	_, err := out.Write(append([]byte(ArchiveMagic), ArchiveVersion))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	lb := make([]byte, binary.MaxVarintLen64)
+	for _, h := range t.topological() {
+		o, err := t.Get(h)
+		if err != nil {
+			return n, errors.New("Could not read site " + h.String() + ": " + err.Error())
+		}
+		typ, err := datastore.Lookup(o.Site.Type)
+		if err != nil {
+			return n, err
+		}
+		if typ.Virtual {
+			continue
+		}
+		data, err := o.Data.Serialize()
+		if err != nil {
+			return n, err
+		}
+		b, err := msgpack.Marshal(&archiveRecord{Site: o.Site.Serialize(), Data: data, Tip: t.HasTip(h)})
+		if err != nil {
+			return n, err
+		}
+		_, err = out.Write(lb[:binary.PutUvarint(lb, uint64(len(b)))])
+		if err != nil {
+			return n, err
+		}
+		_, err = out.Write(b)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	trailer := make([]byte, 9)
+	binary.BigEndian.PutUint64(trailer[1:], uint64(n))
+	_, err = out.Write(trailer)
+	if err != nil {
+		return n, err
+	}
+	_, err = bw.Write(sum.Sum(nil))
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+// Import reads an archive written by Export and restores its sites, running the same verification as Restore.
+// The archive is read twice: the records are only injected after the checksum of the complete archive matched,
+// so a corrupt archive adds nothing. Readers which can not seek are staged in a temporary file.
+// Sites already in the tangle are skipped, so an interrupted import can be resumed by importing the same archive again.
+// It returns the number of added sites.
+func (t *Tangle) Import(r io.Reader) (int, error) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		f, err := ioutil.TempFile("", "tangle-import")
+		if err != nil {
+			return 0, err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		_, err = io.Copy(f, r)
+		if err != nil {
+			return 0, err
+		}
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+		rs = f
+	}
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	err = readArchive(rs, nil)
+	if err != nil {
+		return 0, err
+	}
+	_, err = rs.Seek(start, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	err = readArchive(rs, func(b []byte) error {
+		ok, err := t.importRecord(b)
+		if ok {
+			added++
+		}
+		return err
+	})
+	return added, err
+}
+
+// readArchive checks the structure and the checksum of the archive, calling fn for every record if it is not nil
+func readArchive(r io.Reader, fn func([]byte) error) error {
+	sum, _ := blake2b.New256(nil)
+	ar := &archiveReader{r: bufio.NewReader(r), sum: sum}
+	header := make([]byte, len(ArchiveMagic)+1)
+	if _, err := io.ReadFull(ar, header); err != nil {
+		return ErrInvalidArchive
+	}
+	if string(header[:len(ArchiveMagic)]) != ArchiveMagic {
+		return ErrInvalidArchive
+	}
+	if header[len(ArchiveMagic)] != ArchiveVersion {
+		return ErrArchiveVersion
+	}
+	records := uint64(0)
+	for {
+		l, err := binary.ReadUvarint(ar)
+		if err != nil {
+			return ErrInvalidArchive
+		}
+		if l == 0 {
+			break
+		}
+		if l > MaxArchiveRecord {
+			return ErrInvalidArchive
+		}
+		b := make([]byte, l)
+		if _, err := io.ReadFull(ar, b); err != nil {
+			return ErrInvalidArchive
+		}
+		records++
+		if fn != nil {
+			err = fn(b)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cb := make([]byte, 8)
+	if _, err := io.ReadFull(ar, cb); err != nil {
+		return ErrInvalidArchive
+	}
+	expected := sum.Sum(nil)
+	checksum := make([]byte, len(expected))
+	if _, err := io.ReadFull(ar.r, checksum); err != nil {
+		return ErrInvalidArchive
+	}
+	if binary.BigEndian.Uint64(cb) != records || !bytes.Equal(checksum, expected) {
+		return ErrArchiveChecksum
+	}
+	return nil
+}
+
+// importRecord injects a single archived site. It returns false if the site was already known
+func (t *Tangle) importRecord(b []byte) (bool, error) {
+	rec := archiveRecord{}
+	err := msgpack.Unmarshal(b, &rec)
+	if err != nil {
+		return false, err
+	}
+	s := &site.Site{}
+	err = s.Deserialize(rec.Site)
+	if err != nil {
+		return false, err
+	}
+	if t.Has(s.Hash()) {
+		return false, nil
+	}
+	for _, p := range s.Validates {
+		if !t.Has(p) {
+			return false, errors.New("Site " + s.Hash().String() + " validates unknown site " + p.String())
+		}
+	}
+	typ, err := datastore.Lookup(s.Type)
+	if err != nil {
+		return false, err
+	}
+	d := typ.New()
+	err = d.Deserialize(rec.Data)
+	if err != nil {
+		return false, err
+	}
+	return true, t.Restore(&Object{Site: s, Data: d}, rec.Tip)
+}
+
+// topological returns all readable hashes ordered so that every site comes after the sites it validates
+func (t *Tangle) topological() []hash.Hash {
+	return t.graph.topological()
+}
+
+// archiveReader feeds all bytes read into the checksum
+type archiveReader struct {
+	r   *bufio.Reader
+	sum io.Writer
+}
+
+func (a *archiveReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	a.sum.Write(p[:n])
+	return n, err
+}
+
+func (a *archiveReader) ReadByte() (byte, error) {
+	b, err := a.r.ReadByte()
+	if err == nil {
+		a.sum.Write([]byte{b})
+	}
+	return b, err
+}
@@ -0,0 +1,79 @@
+package tangle
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport(t *testing.T) {
+	src, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer src.Close()
+	randomTangle(t, src, 20, 3)
+	buff := bytes.NewBuffer(nil)
+	n, err := src.Export(buff)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, n)
+	partial := append([]byte{}, buff.Bytes()...)
+
+	randomTangle(t, src, 20, 4)
+	buff.Reset()
+	n, err = src.Export(buff)
+	assert.NoError(t, err)
+	assert.Equal(t, 40, n)
+	archive := buff.Bytes()
+
+	dst, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer dst.Close()
+
+	// a truncated archive adds nothing
+	n, err = dst.Import(bytes.NewReader(archive[:len(archive)/2]))
+	assert.Equal(t, ErrInvalidArchive, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 2, dst.Size())
+
+	// a tampered record is detected before any site is added
+	corrupt := append([]byte{}, archive...)
+	corrupt[len(corrupt)/2] ^= 0xff
+	n, err = dst.Import(bytes.NewReader(corrupt))
+	assert.Equal(t, ErrArchiveChecksum, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 2, dst.Size())
+
+	// importing the complete archive after an earlier one only adds the remaining sites
+	n, err = dst.Import(bytes.NewReader(partial))
+	assert.NoError(t, err)
+	assert.Equal(t, 20, n)
+	n, err = dst.Import(bytes.NewBuffer(archive))
+	assert.NoError(t, err)
+	assert.Equal(t, 20, n)
+	assert.Equal(t, src.Size(), dst.Size())
+	assert.ElementsMatch(t, src.tipHashes(), dst.tipHashes())
+	for _, h := range src.Hashes() {
+		so, err := src.Get(h)
+		assert.NoError(t, err)
+		do, err := dst.Get(h)
+		assert.NoError(t, err)
+		assert.Equal(t, so, do)
+	}
+	r, err := dst.Check(false)
+	assert.NoError(t, err)
+	assert.True(t, r.OK())
+
+	n, err = dst.Import(bytes.NewReader(archive))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	corrupt = append([]byte{}, archive...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	_, err = dst.Import(bytes.NewReader(corrupt))
+	assert.Equal(t, ErrArchiveChecksum, err)
+
+	corrupt = append([]byte{}, archive...)
+	corrupt[len(ArchiveMagic)] = ArchiveVersion + 1
+	_, err = dst.Import(bytes.NewReader(corrupt))
+	assert.Equal(t, ErrArchiveVersion, err)
+}
@@ -2,13 +2,20 @@ package datastore
 
 import (
 	"errors"
+	"time"
 
-	bolt "github.com/coreos/bbolt"
 	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/store"
+	bolt "go.etcd.io/bbolt"
 )
 
 var (
 	bucketname = []byte("data")
+
+	// ErrNotFound is returned when no data is stored for the hash
+	ErrNotFound = store.ErrNotFound
+	// ErrCorrupt is returned when the stored data can not be deserialized
+	ErrCorrupt = store.ErrCorrupt
 )
 
 // Serializable allows for the storage of any kind of data
@@ -21,7 +28,32 @@ type Serializable interface {
 	ReInit() error
 }
 
-// Store is responsible for storing the actual data on the tangle
+// Authored is implemented by payloads signed by a known author
+type Authored interface {
+	// Author returns the fingerprint of the signing key
+	Author() string
+}
+
+// Indexable is implemented by payloads containing text for the search index
+type Indexable interface {
+	// Text returns the searchable content
+	Text() string
+}
+
+// Dated is implemented by payloads carrying a creation date
+type Dated interface {
+	// Date returns the creation date, zero if unknown
+	Date() time.Time
+}
+
+// Described is implemented by payloads with additional searchable fields
+type Described interface {
+	// Meta returns the values of each field, keyed by the lowercase field name
+	Meta() map[string][]string
+}
+
+// Store is the standalone payload database of older versions.
+// Payloads are now kept by the site store, this database is only read to migrate them.
 type Store struct {
 	db *bolt.DB
 }
@@ -61,15 +93,26 @@ func (s *Store) Put(e Serializable) error {
 
 // Get retrieves the serialized object
 func (s *Store) Get(dest Serializable, h hash.Hash) error {
-	var buff []byte
-	err := s.db.View(func(tx *bolt.Tx) error {
-		buff = tx.Bucket(bucketname).Get(h.Slice())
+	return s.db.View(func(tx *bolt.Tx) error {
+		buff := tx.Bucket(bucketname).Get(h.Slice())
+		if buff == nil {
+			return ErrNotFound
+		}
+		if dest.Deserialize(buff) != nil {
+			return ErrCorrupt
+		}
 		return nil
 	})
-	if err != nil {
-		return err
-	}
-	return dest.Deserialize(buff)
+}
+
+// ForEach calls fn for every stored payload until fn returns an error.
+// The value is only valid during the call
+func (s *Store) ForEach(fn func(h hash.Hash, v []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketname).ForEach(func(k, v []byte) error {
+			return fn(hash.FromSlice(k), v)
+		})
+	})
 }
 
 // Close closes the db connection
@@ -0,0 +1,86 @@
+package datastore
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrUnknownType is returned when a payload type has not been registered
+	ErrUnknownType = errors.New("Type not registered")
+
+	registryMu sync.RWMutex
+	registry   = make(map[string]Type)
+)
+
+// Type describes a kind of payload that can be stored on the tangle
+type Type struct {
+	// Name is the identifier stored in site.Site.Type
+	Name string
+	// New returns an empty instance, ready to be deserialized or bound from JSON
+	New func() Serializable
+	// Validate is an optional check run before a payload is accepted
+	Validate func(Serializable) error
+	// Public types can be submitted through the API and received from other nodes
+	Public bool
+	// Virtual types are not kept in the store, New already returns the complete payload
+	Virtual bool
+}
+
+// Register makes a payload type available to all layers.
+// It panics when the name is empty or has already been registered.
+func Register(t Type) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if t.Name == "" || t.New == nil {
+		panic("datastore: Register called with incomplete type")
+	}
+	if _, dup := registry[t.Name]; dup {
+		panic("datastore: Register called twice for type " + t.Name)
+	}
+	registry[t.Name] = t
+}
+
+// Lookup returns the registered type with the given name
+func Lookup(name string) (Type, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	t, ok := registry[name]
+	if !ok {
+		return Type{}, ErrUnknownType
+	}
+	return t, nil
+}
+
+// LookupPublic returns the registered type, failing for types that are not public
+func LookupPublic(name string) (Type, error) {
+	t, err := Lookup(name)
+	if err != nil {
+		return t, err
+	}
+	if !t.Public {
+		return Type{}, ErrUnknownType
+	}
+	return t, nil
+}
+
+// Types returns the sorted names of all registered types
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	ns := []string{}
+	for n := range registry {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return ns
+}
+
+// Check runs the optional validation step of the type
+func (t Type) Check(s Serializable) error {
+	if t.Validate == nil {
+		return nil
+	}
+	return t.Validate(s)
+}
@@ -0,0 +1,31 @@
+package datastore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	_, err := Lookup("registrytest")
+	assert.Equal(t, ErrUnknownType, err)
+
+	errInvalid := errors.New("invalid")
+	Register(Type{
+		Name:     "registrytest",
+		New:      func() Serializable { return nil },
+		Validate: func(Serializable) error { return errInvalid },
+	})
+	typ, err := Lookup("registrytest")
+	assert.NoError(t, err)
+	assert.Equal(t, errInvalid, typ.Check(nil))
+	assert.Contains(t, Types(), "registrytest")
+
+	_, err = LookupPublic("registrytest")
+	assert.Equal(t, ErrUnknownType, err)
+
+	assert.Panics(t, func() {
+		Register(Type{Name: "registrytest", New: func() Serializable { return nil }})
+	})
+}
@@ -0,0 +1,62 @@
+package tangle
+
+import (
+	"github.com/u-speak/core/tangle/hash"
+)
+
+const (
+	// RateWindow is the number of seconds before a site in which the arrival rate is measured
+	RateWindow = 60
+	// TargetRate is the number of sites per RateWindow the base difficulty is meant for
+	TargetRate = 64
+	// MaxDifficultyIncrease limits the additional bits required during spam waves
+	MaxDifficultyIncrease = 16
+	// MaxClockDrift is the number of seconds the time of a site may be ahead of the local clock.
+	// It is kept well below RateWindow, so clocks running ahead hardly shift the measured rate
+	MaxClockDrift = 15
+	// PayloadAllowance is the serialized payload size in bytes covered by the base difficulty
+	PayloadAllowance = 16 << 10
+)
+
+// RequiredDifficulty returns the number of leading zero bits needed by a new site validating the parents
+// with a serialized payload of size bytes. It is the configured difficulty plus the RateDifficulty
+// of the parents and the PayloadDifficulty of the size.
+// Sites without time were created before both terms were introduced and only need the configured difficulty,
+// so history received through Splice or Import stays valid. New sites without time are rejected, see Restore.
+func (t *Tangle) RequiredDifficulty(parents []hash.Hash, size int) int {
+	return t.difficulty + t.RateDifficulty(parents) + PayloadDifficulty(size)
+}
+
+// RateDifficulty returns the additional bits required because of the arrival rate.
+// The rate is the number of sites in the past cone of the parents created within RateWindow seconds
+// before the newest parent. The time of the new site itself is chosen by its miner and not used.
+// Every doubling beyond TargetRate adds one bit, up to MaxDifficultyIncrease.
+// The past cone of a site never changes, so all nodes agree on the result.
+func (t *Tangle) RateDifficulty(parents []hash.Hash) int {
+	newest := int64(0)
+	for _, p := range parents {
+		if pt, ok := t.graph.time(p); ok && pt > newest {
+			newest = pt
+		}
+	}
+	if newest == 0 {
+		return 0
+	}
+	n := t.graph.recent(parents, newest-RateWindow, TargetRate<<MaxDifficultyIncrease)
+	extra := 0
+	for n >= 2*TargetRate && extra < MaxDifficultyIncrease {
+		n /= 2
+		extra++
+	}
+	return extra
+}
+
+// PayloadDifficulty returns the additional bits required for a serialized payload of size bytes.
+// Storing and replicating large payloads costs more, so every doubling beyond PayloadAllowance adds one bit.
+func PayloadDifficulty(size int) int {
+	extra := 0
+	for s := PayloadAllowance; s < size; s *= 2 {
+		extra++
+	}
+	return extra
+}
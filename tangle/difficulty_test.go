@@ -0,0 +1,133 @@
+package tangle
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+)
+
+// busyGraph returns a graph with the given number of sites on each level, created one level per second,
+// and the sites of the last level
+func busyGraph(levels, width int) (*graph, []hash.Hash) {
+	g := newGraph()
+	id := func(l, i int) hash.Hash { return hash.Hash{1, byte(l), byte(i >> 8), byte(i)} }
+	for l := 0; l < levels; l++ {
+		for i := 0; i < width; i++ {
+			ps := []hash.Hash{}
+			if l > 0 {
+				ps = append(ps, id(l-1, i), id(l-1, (i+1)%width))
+			}
+			g.load(id(l, i), ps, MinimumDifficulty, int64(l+1))
+		}
+	}
+	top := []hash.Hash{}
+	for i := 0; i < width; i++ {
+		top = append(top, id(levels-1, i))
+	}
+	return g, top
+}
+
+func TestRequiredDifficulty(t *testing.T) {
+	tngl := Tangle{}
+	assert.NoError(t, tngl.Init(Options{Store: ms(), Difficulty: 10}))
+	assert.Equal(t, 10, tngl.RequiredDifficulty(tngl.Genesis(), 0))
+	assert.Equal(t, 10, tngl.RequiredDifficulty([]hash.Hash{{42}}, 100))
+
+	levels := 2 * RateWindow
+	for width, extra := range map[int]int{1: 0, 2: 0, 4: 1, 8: 2, 64: 5} {
+		g, top := busyGraph(levels, width)
+		tngl.graph = g
+		assert.Equal(t, 10+extra, tngl.RequiredDifficulty(top, 0), "width %d", width)
+		assert.Equal(t, extra, tngl.RateDifficulty(top))
+		assert.Equal(t, width*(RateWindow+1), g.recent(append(top, top...), int64(levels-RateWindow), levels*width))
+	}
+	g, top := busyGraph(3, 64)
+	tngl.graph = g
+	assert.Equal(t, 11, tngl.RequiredDifficulty(top, 0))
+
+	// the rate is measured before the newest parent, so claiming a later time does not help
+	g, top = busyGraph(levels, 8)
+	tngl.graph = g
+	d := dd("late")
+	h, _ := d.Hash()
+	for _, ts := range []int64{int64(levels), int64(levels) + MaxClockDrift, int64(levels) + 10*RateWindow} {
+		s := &site.Site{Content: h, Type: "dummy", Validates: top, Time: ts}
+		for s.Mine(10); s.Hash().Difficulty() >= 12; s.Mine(10) {
+			s.Nonce++
+		}
+		_, err := tngl.verifySite(s, 0)
+		assert.Equal(t, ErrWeightTooLow, err, "time %d", ts)
+		s.Mine(12)
+		w, err := tngl.verifySite(s, 0)
+		assert.NoError(t, err, "time %d", ts)
+		assert.Equal(t, s.Hash().Difficulty(), w, "time %d", ts)
+	}
+
+	// leaving out the time does not avoid the rate either
+	untimed := &Object{Site: &site.Site{Content: h, Type: "dummy", Validates: top}, Data: d}
+	untimed.Site.Mine(10)
+	assert.Equal(t, ErrMissingTime, tngl.Verify(untimed, false))
+	assert.Equal(t, ErrTimeBeforeParents, tngl.Verify(untimed, true))
+}
+
+func TestVerifyTime(t *testing.T) {
+	tngl, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	now := time.Now().Unix()
+	newSite := func(name string, ts int64, validates []hash.Hash) *Object {
+		d := dd(name)
+		h, _ := d.Hash()
+		o := &Object{Site: &site.Site{Content: h, Validates: validates, Type: "dummy", Time: ts}, Data: d}
+		o.Site.Mine(MinimumDifficulty)
+		return o
+	}
+	timed := newSite("timed", now, tngl.Genesis())
+	assert.NoError(t, tngl.Add(timed))
+	other := newSite("other", now, tngl.Genesis())
+	assert.NoError(t, tngl.Inject(other, true))
+	parents := []hash.Hash{timed.Site.Hash(), other.Site.Hash()}
+
+	assert.Equal(t, ErrMissingTime, tngl.Add(newSite("untimed", 0, parents)))
+	assert.Equal(t, ErrTimeBeforeParents, tngl.Restore(newSite("untimed", 0, parents), true))
+	assert.Equal(t, ErrTimeBeforeParents, tngl.Add(newSite("older", now-1, parents)))
+	assert.Equal(t, ErrTimeInFuture, tngl.Add(newSite("future", now+2*MaxClockDrift, parents)))
+	assert.Equal(t, ErrTimeInFuture, tngl.Add(newSite("negative", -1, tngl.Genesis())))
+	assert.NoError(t, tngl.Add(newSite("later", now+1, parents)))
+}
+
+func TestPayloadDifficulty(t *testing.T) {
+	for size, extra := range map[int]int{0: 0, 10: 0, PayloadAllowance: 0, PayloadAllowance + 1: 1, 2 * PayloadAllowance: 1, 2*PayloadAllowance + 1: 2, 4900000: 9} {
+		assert.Equal(t, extra, PayloadDifficulty(size), "size %d", size)
+	}
+	tngl, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	now := time.Now().Unix()
+	assert.Equal(t, MinimumDifficulty+9, tngl.RequiredDifficulty(tngl.Genesis(), 4900000))
+
+	d := dd(strings.Repeat("x", 3*PayloadAllowance))
+	h, _ := d.Hash()
+	lowWork := func(s *site.Site) {
+		for s.Mine(MinimumDifficulty); s.Hash().Difficulty() >= MinimumDifficulty+2; s.Mine(MinimumDifficulty) {
+			s.Nonce++
+		}
+	}
+	o := &Object{Site: &site.Site{Content: h, Validates: tngl.Genesis(), Type: "dummy", Time: now}, Data: d}
+	lowWork(o.Site)
+	assert.Equal(t, ErrWeightTooLow, tngl.Add(o))
+	o.Site.Mine(MinimumDifficulty + 2)
+	assert.NoError(t, tngl.Add(o))
+
+	// new sites can not leave out the time to avoid the payload term, even next to the untimed genesis
+	old := &Object{Site: &site.Site{Content: h, Validates: tngl.Genesis(), Type: "dummy"}, Data: d}
+	lowWork(old.Site)
+	assert.Equal(t, ErrMissingTime, tngl.Inject(old, false))
+	assert.Equal(t, ErrMissingTime, tngl.Verify(old, false))
+	// sites without time predate the payload term, so history received through Import or Splice stays valid
+	assert.NoError(t, tngl.Restore(old, false))
+}
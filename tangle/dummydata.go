@@ -29,3 +29,7 @@ func (d *dummydata) JSON() error {
 func (d *dummydata) ReInit() error {
 	return nil
 }
+
+func (d *dummydata) Text() string {
+	return d.content
+}
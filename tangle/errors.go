@@ -2,14 +2,46 @@ package tangle
 
 import (
 	"errors"
-	"strconv"
+
+	"github.com/u-speak/core/tangle/store"
 )
 
 var (
-	// ErrWeightTooLow is returned when the weight does not exceed MinimumWeight
-	ErrWeightTooLow = errors.New("Weight too low. Has to be > " + strconv.Itoa(MinimumWeight))
+	// ErrNotFound is returned for sites which are not part of the tangle
+	ErrNotFound = store.ErrNotFound
+	// ErrCorrupt is returned when a site or its payload can not be read from the store
+	ErrCorrupt = store.ErrCorrupt
+	// ErrWeightTooLow is returned when the hash has fewer leading zero bits than the difficulty requires
+	ErrWeightTooLow = errors.New("Weight too low. Difficulty has to be at least the required number of leading zero bits")
+	// ErrWrongPoW is returned when the site was mined with another proof of work algorithm than the network uses
+	ErrWrongPoW = errors.New("Site uses the wrong proof of work algorithm")
+	// ErrMissingTime is returned for new sites without time. Only history may contain sites without time
+	ErrMissingTime = errors.New("Site has no time")
+	// ErrTimeBeforeParents is returned when the site is older than a site it validates.
+	// Sites without time count as older than all sites with time
+	ErrTimeBeforeParents = errors.New("Site is older than the sites it validates")
+	// ErrTimeInFuture is returned when the time of the site is negative or too far ahead of the local clock
+	ErrTimeInFuture = errors.New("Site time is invalid or too far in the future")
 	// ErrNotValidating is returned when the site does not validate any current tip
 	ErrNotValidating = errors.New("Site does not validate any current tip")
 	// ErrTooFewValidations is returned when the site does not validate enough sites
 	ErrTooFewValidations = errors.New("Site does not validate enough sites")
+	// ErrNoPayload is returned when a site of a non virtual type is added without its payload
+	ErrNoPayload = errors.New("Site has no payload")
+	// ErrGenesisMismatch is returned by Init when the store does not contain the configured genesis sites
+	ErrGenesisMismatch = errors.New("Store belongs to a different network")
+	// ErrUnreadable is reported by Check for sites which can not be deserialized
+	ErrUnreadable = errors.New("Site can not be read")
+	// ErrHashMismatch is reported by Check when a site is stored under a different hash
+	ErrHashMismatch = errors.New("Site hash does not match its key")
+	// ErrContentMismatch is reported by Check when the payload does not match the content hash
+	ErrContentMismatch = errors.New("Payload does not match content hash")
+	// ErrMissingParent is reported by Check when a validated site does not exist
+	ErrMissingParent = errors.New("Validated site does not exist")
+	// ErrBrokenParent is reported by Check when a validated site is broken itself
+	ErrBrokenParent = errors.New("Validated site is broken")
+	// ErrStaleTip is reported by Check for tips which are validated or do not exist
+	ErrStaleTip = errors.New("Tip is validated by another site or does not exist")
+	// ErrMissingTip is reported by Check for sites without approvers which are not tips
+	ErrMissingTip = errors.New("Site without approvers is not a tip")
 )
@@ -0,0 +1,256 @@
+package tangle
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/u-speak/core/tangle/datastore"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultEventBuffer is the default capacity of a subscription channel
+	DefaultEventBuffer = 64
+	// MaxPendingConfirmations limits how many unconfirmed sites are watched for SiteConfirmed events
+	MaxPendingConfirmations = 1000
+	// DefaultConfirmationInterval is the default interval at which pending sites are checked for confirmation
+	DefaultConfirmationInterval = 10 * time.Second
+)
+
+// EventKind specifies what happened inside the tangle
+type EventKind int
+
+const (
+	// SiteAdded is emitted for every site added to the tangle
+	SiteAdded EventKind = iota
+	// TipsChanged is emitted whenever the set of tips changes
+	TipsChanged
+	// SiteConfirmed is emitted once the confidence of a site reaches the confirmation threshold
+	SiteConfirmed
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case SiteAdded:
+		return "site added"
+	case TipsChanged:
+		return "tips changed"
+	case SiteConfirmed:
+		return "site confirmed"
+	}
+	return "unknown"
+}
+
+// Event is delivered to subscribers
+type Event struct {
+	Kind EventKind
+	// Site is set for SiteAdded and SiteConfirmed
+	Site *site.Site
+	// Author is the author of the payload, if it implements datastore.Authored
+	Author string
+	// Tips contains all current tips for TipsChanged
+	Tips []hash.Hash
+}
+
+// Filter selects the events delivered to a subscription.
+// Empty fields match everything, Types and Authors only apply to events carrying a site.
+type Filter struct {
+	Kinds   []EventKind
+	Types   []string
+	Authors []string
+	// Buffer is the capacity of the event channel. Defaults to DefaultEventBuffer
+	Buffer int
+}
+
+func (f Filter) wants(k EventKind) bool {
+	if len(f.Kinds) == 0 {
+		return true
+	}
+	for _, fk := range f.Kinds {
+		if fk == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) match(e Event) bool {
+	if !f.wants(e.Kind) {
+		return false
+	}
+	if e.Site == nil {
+		return true
+	}
+	if len(f.Types) > 0 && !contains(f.Types, e.Site.Type, false) {
+		return false
+	}
+	if len(f.Authors) > 0 && !contains(f.Authors, e.Author, true) {
+		return false
+	}
+	return true
+}
+
+func contains(l []string, s string, fold bool) bool {
+	for _, e := range l {
+		if e == s || fold && strings.EqualFold(e, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription receives the events matching its filter.
+// Events are dropped instead of blocking the tangle when the channel is full.
+type Subscription struct {
+	filter  Filter
+	events  chan Event
+	dropped uint64
+	tangle  *Tangle
+}
+
+// Events returns the channel delivering the events. It is closed by Close
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events lost because the channel was full
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close stops the delivery of events and closes the channel
+func (s *Subscription) Close() {
+	t := s.tangle
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+	if t.subs[s] {
+		delete(t.subs, s)
+		close(s.events)
+	}
+}
+
+// Subscribe returns a subscription for all events matching the filter
+func (t *Tangle) Subscribe(f Filter) *Subscription {
+	if f.Buffer <= 0 {
+		f.Buffer = DefaultEventBuffer
+	}
+	s := &Subscription{filter: f, events: make(chan Event, f.Buffer), tangle: t}
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+	t.subs[s] = true
+	return s
+}
+
+// emit delivers the event to all matching subscriptions. The caller must hold subMu
+func (t *Tangle) emit(e Event) {
+	for s := range t.subs {
+		if !s.filter.match(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// publish emits the events caused by adding o. The caller must hold the write lock
+func (t *Tangle) publish(o *Object, tipsChanged bool) {
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+	if len(t.subs) == 0 {
+		return
+	}
+	e := Event{Kind: SiteAdded, Site: o.Site}
+	if a, ok := o.Data.(datastore.Authored); ok {
+		e.Author = a.Author()
+	}
+	t.emit(e)
+	if tipsChanged {
+		tips := make([]hash.Hash, 0, len(t.tips))
+		for h := range t.tips {
+			tips = append(tips, h)
+		}
+		sortHashes(tips)
+		t.emit(Event{Kind: TipsChanged, Tips: tips})
+	}
+	c := Event{Kind: SiteConfirmed, Site: o.Site, Author: e.Author}
+	for s := range t.subs {
+		if s.filter.match(c) {
+			t.watch(c)
+			break
+		}
+	}
+}
+
+// watch adds the event to the sites waiting for confirmation, evicting the oldest beyond MaxPendingConfirmations.
+// Evicted events count as dropped for the subscriptions they would have been delivered to. The caller must hold subMu
+func (t *Tangle) watch(e Event) {
+	t.pending = append(t.pending, e)
+	if n := len(t.pending) - MaxPendingConfirmations; n > 0 {
+		for _, ev := range t.pending[:n] {
+			for s := range t.subs {
+				if s.filter.match(ev) {
+					atomic.AddUint64(&s.dropped, 1)
+				}
+			}
+		}
+		log.Warnf("Stopped watching %d unconfirmed sites, more than %d are pending", n, MaxPendingConfirmations)
+		t.pending = append([]Event{}, t.pending[n:]...)
+	}
+	select {
+	case t.confirm <- struct{}{}:
+	default:
+	}
+}
+
+// watchConfirmations emits SiteConfirmed events for pending sites until the tangle is closed.
+// Pending sites are checked whenever a site is watched and every interval, as confidence also grows while the tangle is quiet
+func (t *Tangle) watchConfirmations(interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-t.confirm:
+		case <-tick.C:
+		}
+		t.subMu.Lock()
+		pending := append([]Event{}, t.pending...)
+		t.subMu.Unlock()
+		if len(pending) == 0 {
+			continue
+		}
+		tips := t.sampleTips()
+		if len(tips) == 0 {
+			continue
+		}
+		confirmed := make(map[hash.Hash]bool)
+		for _, e := range pending {
+			h := e.Site.Hash()
+			if t.confidence(h, tips) >= t.threshold {
+				confirmed[h] = true
+			}
+		}
+		if len(confirmed) == 0 {
+			continue
+		}
+		t.subMu.Lock()
+		rest := t.pending[:0]
+		for _, e := range t.pending {
+			if confirmed[e.Site.Hash()] {
+				t.emit(e)
+			} else {
+				rest = append(rest, e)
+			}
+		}
+		t.pending = rest
+		t.subMu.Unlock()
+	}
+}
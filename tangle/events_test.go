@@ -0,0 +1,121 @@
+package tangle
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+)
+
+func next(t *testing.T, s *Subscription) Event {
+	select {
+	case e := <-s.Events():
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+	return Event{}
+}
+
+func TestSubscribe(t *testing.T) {
+	tngl, err := New(Options{Store: ms(), TipSelector: NewUniformRandom(rand.NewSource(1))})
+	assert.NoError(t, err)
+	defer tngl.Close()
+
+	all := tngl.Subscribe(Filter{Kinds: []EventKind{SiteAdded, TipsChanged}})
+	posts := tngl.Subscribe(Filter{Types: []string{"post"}, Kinds: []EventKind{SiteAdded}})
+	confirmed := tngl.Subscribe(Filter{Kinds: []EventKind{SiteConfirmed}})
+	gen := tngl.tipHashes()
+	hs := chain(t, tngl, "events", 3, gen[0], gen[1])
+
+	for _, h := range hs {
+		e := next(t, all)
+		assert.Equal(t, SiteAdded, e.Kind)
+		assert.Equal(t, h, e.Site.Hash())
+		e = next(t, all)
+		assert.Equal(t, TipsChanged, e.Kind)
+		assert.Equal(t, []hash.Hash{h}, e.Tips)
+	}
+	seen := make(map[hash.Hash]bool)
+	for len(seen) < len(hs) {
+		e := next(t, confirmed)
+		assert.Equal(t, SiteConfirmed, e.Kind)
+		seen[e.Site.Hash()] = true
+	}
+	assert.Len(t, posts.Events(), 0)
+
+	posts.Close()
+	_, ok := <-posts.Events()
+	assert.False(t, ok)
+	posts.Close()
+}
+
+func TestSubscribeBackpressure(t *testing.T) {
+	tngl, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+
+	s := tngl.Subscribe(Filter{Kinds: []EventKind{SiteAdded}, Buffer: 2})
+	gen := tngl.tipHashes()
+	chain(t, tngl, "backpressure", 5, gen[0], gen[1])
+	assert.Len(t, s.Events(), 2)
+	assert.Equal(t, uint64(3), s.Dropped())
+
+	tngl.Close()
+	n := 0
+	for range s.Events() {
+		n++
+	}
+	assert.Equal(t, 2, n)
+	assert.NotPanics(t, tngl.Close)
+}
+
+func TestFilter(t *testing.T) {
+	s := &site.Site{Type: "post"}
+	f := Filter{Types: []string{"post"}, Authors: []string{"abcdef"}}
+	assert.True(t, f.match(Event{Kind: SiteAdded, Site: s, Author: "ABCDEF"}))
+	assert.False(t, f.match(Event{Kind: SiteAdded, Site: s, Author: "012345"}))
+	assert.False(t, f.match(Event{Kind: SiteAdded, Site: &site.Site{Type: "image"}, Author: "ABCDEF"}))
+	assert.True(t, f.match(Event{Kind: TipsChanged}))
+	f.Kinds = []EventKind{SiteConfirmed}
+	assert.False(t, f.match(Event{Kind: SiteAdded, Site: s, Author: "ABCDEF"}))
+	assert.True(t, f.match(Event{Kind: SiteConfirmed, Site: s, Author: "ABCDEF"}))
+}
+
+func TestPendingConfirmations(t *testing.T) {
+	tngl, err := New(Options{Store: ms(), ConfirmationThreshold: 2})
+	assert.NoError(t, err)
+	defer tngl.Close()
+
+	posts := tngl.Subscribe(Filter{Kinds: []EventKind{SiteConfirmed}, Types: []string{"post"}})
+	gen := tngl.tipHashes()
+	chain(t, tngl, "pending", 2, gen[0], gen[1])
+	tngl.subMu.Lock()
+	assert.Empty(t, tngl.pending, "sites not matching any filter are not watched")
+	for i := 0; i < MaxPendingConfirmations+5; i++ {
+		tngl.watch(Event{Kind: SiteConfirmed, Site: &site.Site{Type: "post", Nonce: uint64(i)}})
+	}
+	assert.Len(t, tngl.pending, MaxPendingConfirmations)
+	assert.Equal(t, uint64(5), tngl.pending[0].Site.Nonce)
+	tngl.subMu.Unlock()
+	assert.Equal(t, uint64(5), posts.Dropped())
+}
+
+func TestConfirmationInterval(t *testing.T) {
+	tngl, err := New(Options{Store: ms(), ConfirmationInterval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	gen := tngl.tipHashes()
+	hs := chain(t, tngl, "quiet", 3, gen[0], gen[1])
+
+	confirmed := tngl.Subscribe(Filter{Kinds: []EventKind{SiteConfirmed}})
+	s, err := tngl.GetSite(hs[0])
+	assert.NoError(t, err)
+	tngl.subMu.Lock()
+	tngl.pending = append(tngl.pending, Event{Kind: SiteConfirmed, Site: s})
+	tngl.subMu.Unlock()
+	e := next(t, confirmed)
+	assert.Equal(t, hs[0], e.Site.Hash())
+}
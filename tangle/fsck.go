@@ -0,0 +1,215 @@
+package tangle
+
+import (
+	"errors"
+
+	"github.com/u-speak/core/tangle/datastore"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+	"github.com/u-speak/core/tangle/store"
+)
+
+// ErrNoQuarantine is returned by Check when broken sites should be repaired, but the store does not implement store.Quarantiner
+var ErrNoQuarantine = errors.New("Store does not support quarantining sites")
+
+// Problem is a single inconsistency found by Check
+type Problem struct {
+	Hash hash.Hash
+	Err  error
+}
+
+// Report summarizes the result of Check
+type Report struct {
+	// Sites is the number of checked sites
+	Sites    int
+	Problems []Problem
+	// Quarantined lists the sites removed by the repair
+	Quarantined []hash.Hash
+	// TipsRebuilt is set when the repair changed the stored tips
+	TipsRebuilt bool
+}
+
+// OK is true if no problems were found
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *Report) add(h hash.Hash, err error) {
+	r.Problems = append(r.Problems, Problem{Hash: h, Err: err})
+}
+
+// Check verifies the consistency of the sites and payloads in the store.
+// Every site has to be stored under its own hash, reference an existing payload matching its content hash
+// which passes the validation of its type, and validate existing sites.
+// Sites validating broken sites are broken as well. The stored tips have to be exactly the sites without approvers.
+// In repair mode broken sites are quarantined and the tips are rebuilt.
+func (t *Tangle) Check(repair bool) (*Report, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := &Report{}
+	hs := []hash.Hash{}
+	exists := make(map[hash.Hash]bool)
+	sites := make(map[hash.Hash]*site.Site)
+	err := t.store.ForEach(func(h hash.Hash, s *site.Site) bool {
+		hs = append(hs, h)
+		exists[h] = true
+		if s != nil {
+			sites[h] = s
+		}
+		return true
+	})
+	if err != nil {
+		return r, err
+	}
+	sortHashes(hs)
+	r.Sites = len(hs)
+	broken := make(map[hash.Hash]bool)
+	for _, h := range hs {
+		s := sites[h]
+		if s == nil {
+			r.add(h, ErrUnreadable)
+			broken[h] = true
+			continue
+		}
+		if s.Hash() != h {
+			r.add(h, ErrHashMismatch)
+			broken[h] = true
+			continue
+		}
+		if err := t.checkPayload(s); err != nil {
+			r.add(h, err)
+			broken[h] = true
+		}
+	}
+	for _, h := range hs {
+		s, ok := sites[h]
+		if !ok || broken[h] {
+			continue
+		}
+		for _, v := range s.Validates {
+			if !exists[v] {
+				r.add(h, ErrMissingParent)
+				broken[h] = true
+				break
+			}
+			if sites[v] == nil {
+				r.add(h, ErrBrokenParent)
+				broken[h] = true
+				break
+			}
+		}
+	}
+	bound := []hash.Hash{}
+	for _, h := range hs {
+		if broken[h] {
+			bound = append(bound, h)
+		}
+	}
+	for len(bound) > 0 {
+		b := bound[len(bound)-1]
+		bound = bound[:len(bound)-1]
+		as := t.store.Approvers(b)
+		sortHashes(as)
+		for _, a := range as {
+			if broken[a] {
+				continue
+			}
+			r.add(a, ErrBrokenParent)
+			broken[a] = true
+			bound = append(bound, a)
+		}
+	}
+
+	expected := []hash.Hash{}
+	isExpected := make(map[hash.Hash]bool)
+	for _, h := range hs {
+		if broken[h] || sites[h] == nil {
+			continue
+		}
+		approved := false
+		for _, a := range t.store.Approvers(h) {
+			if !broken[a] {
+				approved = true
+				break
+			}
+		}
+		if !approved {
+			expected = append(expected, h)
+			isExpected[h] = true
+		}
+	}
+	stored := t.store.GetTips()
+	sortHashes(stored)
+	isStored := make(map[hash.Hash]bool)
+	stale := []hash.Hash{}
+	for _, h := range stored {
+		isStored[h] = true
+		if !isExpected[h] {
+			stale = append(stale, h)
+			if !broken[h] {
+				r.add(h, ErrStaleTip)
+			}
+		}
+	}
+	missing := []hash.Hash{}
+	for _, h := range expected {
+		if !isStored[h] {
+			missing = append(missing, h)
+			r.add(h, ErrMissingTip)
+		}
+	}
+	if !repair || r.OK() {
+		return r, nil
+	}
+
+	if len(broken) > 0 {
+		q, ok := t.store.(store.Quarantiner)
+		if !ok {
+			return r, ErrNoQuarantine
+		}
+		for _, h := range hs {
+			if !broken[h] {
+				continue
+			}
+			err := q.Quarantine(h)
+			if err != nil {
+				return r, err
+			}
+			r.Quarantined = append(r.Quarantined, h)
+		}
+	}
+	if len(missing) > 0 || len(stale) > 0 {
+		t.store.SetTips(missing, stale)
+		r.TipsRebuilt = true
+	}
+	t.load()
+	return r, nil
+}
+
+// checkPayload verifies the payload referenced by the site
+func (t *Tangle) checkPayload(s *site.Site) error {
+	typ, err := datastore.Lookup(s.Type)
+	if err != nil {
+		return err
+	}
+	if typ.Virtual {
+		return nil
+	}
+	p, err := t.store.Payload(s.Content)
+	if err != nil {
+		return err
+	}
+	d := typ.New()
+	err = d.Deserialize(p)
+	if err != nil {
+		return err
+	}
+	ch, err := d.Hash()
+	if err != nil {
+		return err
+	}
+	if ch != s.Content {
+		return ErrContentMismatch
+	}
+	return typ.Check(d)
+}
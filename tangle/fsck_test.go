@@ -0,0 +1,99 @@
+package tangle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/datastore"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+	"github.com/u-speak/core/tangle/store"
+	"github.com/u-speak/core/tangle/store/memorystore"
+)
+
+func TestCheck(t *testing.T) {
+	tngl, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	gen := tngl.tipHashes()
+	hs := chain(t, tngl, "check", 3, gen[0], gen[1])
+
+	r, err := tngl.Check(false)
+	assert.NoError(t, err)
+	assert.True(t, r.OK())
+	assert.Equal(t, 5, r.Sites)
+
+	mh, _ := dd("missing").Hash()
+	bad := &site.Site{Content: mh, Type: "dummy", Validates: []hash.Hash{hs[2], hs[1]}}
+	bad.Mine(MinimumDifficulty)
+	assert.NoError(t, tngl.store.Add(bad, nil, nil, nil))
+	cd := dd("child")
+	ch, _ := cd.Hash()
+	child := &site.Site{Content: ch, Type: "dummy", Validates: []hash.Hash{bad.Hash(), hs[2]}}
+	child.Mine(MinimumDifficulty)
+	assert.NoError(t, tngl.store.Add(child, []byte("child"), nil, nil))
+	tngl.store.SetTips([]hash.Hash{hs[0]}, nil)
+
+	r, err = tngl.Check(false)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Problem{
+		{Hash: bad.Hash(), Err: datastore.ErrNotFound},
+		{Hash: child.Hash(), Err: ErrBrokenParent},
+		{Hash: hs[0], Err: ErrStaleTip},
+	}, r.Problems)
+	assert.Empty(t, r.Quarantined)
+
+	r, err = tngl.Check(true)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []hash.Hash{bad.Hash(), child.Hash()}, r.Quarantined)
+	assert.True(t, r.TipsRebuilt)
+	assert.False(t, tngl.HasTip(hs[0]))
+	assert.True(t, tngl.HasTip(hs[2]))
+	_, err = tngl.GetSite(bad.Hash())
+	assert.Equal(t, ErrNotFound, err)
+
+	r, err = tngl.Check(false)
+	assert.NoError(t, err)
+	assert.True(t, r.OK())
+	assert.Equal(t, 5, r.Sites)
+}
+
+// unreadableStore reports a single site as unreadable
+type unreadableStore struct {
+	*memorystore.MemoryStore
+	unreadable hash.Hash
+}
+
+func (u *unreadableStore) Get(h hash.Hash) (*site.Site, error) {
+	if h == u.unreadable {
+		return nil, store.ErrCorrupt
+	}
+	return u.MemoryStore.Get(h)
+}
+
+func (u *unreadableStore) ForEach(fn func(hash.Hash, *site.Site) bool) error {
+	return u.MemoryStore.ForEach(func(h hash.Hash, s *site.Site) bool {
+		if h == u.unreadable {
+			s = nil
+		}
+		return fn(h, s)
+	})
+}
+
+func TestCheckUnreadableParent(t *testing.T) {
+	st := &unreadableStore{MemoryStore: ms()}
+	tngl, err := New(Options{Store: st})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	gen := tngl.tipHashes()
+	hs := chain(t, tngl, "unreadable", 3, gen[0], gen[1])
+	st.unreadable = hs[1]
+
+	r, err := tngl.Check(false)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Problem{
+		{Hash: hs[1], Err: ErrUnreadable},
+		{Hash: hs[2], Err: ErrBrokenParent},
+		{Hash: hs[0], Err: ErrMissingTip},
+	}, r.Problems)
+}
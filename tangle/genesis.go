@@ -1,9 +1,24 @@
 package tangle
 
 import (
+	"strconv"
+
+	"github.com/u-speak/core/tangle/datastore"
 	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
 )
 
+// DefaultNetwork identifies the main network
+const DefaultNetwork = "mainnet"
+
+func init() {
+	datastore.Register(datastore.Type{
+		Name:    "genesis",
+		New:     func() datastore.Serializable { return &genesis{} },
+		Virtual: true,
+	})
+}
+
 type genesis struct {
 	Content string `json:"content"`
 }
@@ -29,3 +44,25 @@ func (d *genesis) JSON() error {
 func (d *genesis) ReInit() error {
 	return nil
 }
+
+// DefaultGenesis returns the genesis sites of the main network
+func DefaultGenesis() []*site.Site {
+	return []*site.Site{
+		{Content: hash.Hash{24, 67, 68, 72, 132, 181}, Nonce: 373, Type: "genesis"},
+		{Content: hash.Hash{24, 67, 68, 72, 132, 182}, Nonce: 510, Type: "genesis"},
+	}
+}
+
+// NewGenesis derives the genesis sites of a network from the seed. The empty seed returns DefaultGenesis
+func NewGenesis(seed string) []*site.Site {
+	if seed == "" {
+		return DefaultGenesis()
+	}
+	gs := []*site.Site{}
+	for i := 0; i < MinimumValidations; i++ {
+		g := &site.Site{Content: hash.New([]byte("GENESIS:" + seed + ":" + strconv.Itoa(i))), Type: "genesis"}
+		g.Mine(MinimumDifficulty)
+		gs = append(gs, g)
+	}
+	return gs
+}
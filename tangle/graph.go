@@ -0,0 +1,278 @@
+package tangle
+
+import (
+	"sync"
+
+	"github.com/u-speak/core/tangle/hash"
+)
+
+// graph is an in-memory copy of the tangle structure.
+// It caches the cumulative weight of sites and keeps it up to date as new sites are added.
+type graph struct {
+	mu       sync.RWMutex
+	vertices map[hash.Hash]*vertex
+}
+
+type vertex struct {
+	parents  []hash.Hash
+	children []hash.Hash
+	// loaded is false for sites only known as the parent of another site
+	loaded bool
+	// weight is the own weight of the site
+	weight int
+	// time is the creation time of the site in unix seconds, zero if unknown
+	time int64
+	// cumulative is the cached cumulative weight, valid if cached is set
+	cumulative int
+	cached     bool
+}
+
+func newGraph() *graph {
+	return &graph{vertices: make(map[hash.Hash]*vertex)}
+}
+
+// vertex returns the vertex for h, creating it when needed. The caller must hold the write lock
+func (g *graph) vertex(h hash.Hash) *vertex {
+	v, ok := g.vertices[h]
+	if !ok {
+		v = &vertex{}
+		g.vertices[h] = v
+	}
+	return v
+}
+
+// load inserts a site without touching any cached weights. It is used to build the graph from a store
+func (g *graph) load(h hash.Hash, parents []hash.Hash, weight int, time int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.insert(h, parents, weight, time)
+}
+
+// add inserts a site and adds its weight to the cached cumulative weight of all ancestors
+func (g *graph) add(h hash.Hash, parents []hash.Hash, weight int, time int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	v := g.insert(h, parents, weight, time)
+	if v == nil {
+		return
+	}
+	if len(v.children) > 0 {
+		// The site arrived after sites validating it, so the ancestors missed their weight
+		for a := range g.ancestors(h) {
+			g.vertices[a].cached = false
+		}
+		return
+	}
+	v.cumulative = v.weight
+	v.cached = true
+	for a := range g.ancestors(h) {
+		av := g.vertices[a]
+		if av.cached {
+			av.cumulative += v.weight
+		}
+	}
+}
+
+// insert links the site to its parents. It returns nil if the site is already known.
+// The caller must hold the write lock
+func (g *graph) insert(h hash.Hash, parents []hash.Hash, weight int, time int64) *vertex {
+	v := g.vertex(h)
+	if v.loaded {
+		return nil
+	}
+	v.loaded = true
+	v.parents = parents
+	v.weight = weight
+	v.time = time
+	for _, p := range parents {
+		pv := g.vertex(p)
+		pv.children = append(pv.children, h)
+	}
+	return v
+}
+
+// ancestors returns all sites directly or indirectly validated by h. The caller must hold a lock
+func (g *graph) ancestors(h hash.Hash) map[hash.Hash]bool {
+	seen := make(map[hash.Hash]bool)
+	bound := append([]hash.Hash{}, g.vertices[h].parents...)
+	for len(bound) > 0 {
+		c := bound[len(bound)-1]
+		bound = bound[:len(bound)-1]
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		if v, ok := g.vertices[c]; ok {
+			bound = append(bound, v.parents...)
+		}
+	}
+	return seen
+}
+
+// descendants returns all sites directly or indirectly validating h. The caller must hold a lock
+func (g *graph) descendants(h hash.Hash) map[hash.Hash]bool {
+	seen := make(map[hash.Hash]bool)
+	bound := append([]hash.Hash{}, g.vertices[h].children...)
+	for len(bound) > 0 {
+		c := bound[len(bound)-1]
+		bound = bound[:len(bound)-1]
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		bound = append(bound, g.vertices[c].children...)
+	}
+	return seen
+}
+
+// weight returns the cumulative weight of h, computing and caching it on first access.
+// It reports false for sites which are not loaded
+func (g *graph) weight(h hash.Hash) (int, bool) {
+	g.mu.RLock()
+	v, ok := g.vertices[h]
+	if !ok || !v.loaded {
+		g.mu.RUnlock()
+		return 0, false
+	}
+	if v.cached {
+		defer g.mu.RUnlock()
+		return v.cumulative, true
+	}
+	g.mu.RUnlock()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if v.cached {
+		return v.cumulative, true
+	}
+	w := v.weight
+	for d := range g.descendants(h) {
+		w += g.vertices[d].weight
+	}
+	v.cumulative = w
+	v.cached = true
+	return w, true
+}
+
+// byteWeight returns the cumulative weight of h counted in whole leading zero bytes, as older nodes weighed sites.
+// It is not cached, as only single sites are looked up. It reports false for sites which are not loaded
+func (g *graph) byteWeight(h hash.Hash) (int, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	v, ok := g.vertices[h]
+	if !ok || !v.loaded {
+		return 0, false
+	}
+	w := v.weight / 8
+	for d := range g.descendants(h) {
+		w += g.vertices[d].weight / 8
+	}
+	return w, true
+}
+
+// recent counts the known sites reachable from the parents which were created at or after since, stopping at limit.
+// Sites are never older than their parents, so the search ends at the first older site of every path
+func (g *graph) recent(parents []hash.Hash, since int64, limit int) int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	seen := make(map[hash.Hash]bool)
+	stack := append([]hash.Hash{}, parents...)
+	for len(stack) > 0 && len(seen) < limit {
+		h := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		v, ok := g.vertices[h]
+		if seen[h] || !ok || !v.loaded || v.time == 0 || v.time < since {
+			continue
+		}
+		seen[h] = true
+		stack = append(stack, v.parents...)
+	}
+	return len(seen)
+}
+
+// time returns the creation time of a known site
+func (g *graph) time(h hash.Hash) (int64, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	v, ok := g.vertices[h]
+	if !ok || !v.loaded {
+		return 0, false
+	}
+	return v.time, true
+}
+
+// parents returns a sorted copy of the sites validated by h
+func (g *graph) parents(h hash.Hash) []hash.Hash {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	v, ok := g.vertices[h]
+	if !ok {
+		return nil
+	}
+	ps := append([]hash.Hash{}, v.parents...)
+	sortHashes(ps)
+	return ps
+}
+
+// children returns a sorted copy of the sites validating h
+func (g *graph) children(h hash.Hash) []hash.Hash {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	v, ok := g.vertices[h]
+	if !ok {
+		return nil
+	}
+	cs := append([]hash.Hash{}, v.children...)
+	sortHashes(cs)
+	return cs
+}
+
+// approving returns the site itself and all sites directly or indirectly validating it
+func (g *graph) approving(h hash.Hash) map[hash.Hash]bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if _, ok := g.vertices[h]; !ok {
+		return map[hash.Hash]bool{}
+	}
+	ds := g.descendants(h)
+	ds[h] = true
+	return ds
+}
+
+// topological returns all loaded sites ordered so that every site comes after the sites it validates.
+// Sites without loaded parents come first, sorted by hash, their children follow in hash order
+func (g *graph) topological() []hash.Hash {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	missing := make(map[hash.Hash]int, len(g.vertices))
+	queue := []hash.Hash{}
+	for h, v := range g.vertices {
+		if !v.loaded {
+			continue
+		}
+		for _, p := range v.parents {
+			if g.vertices[p].loaded {
+				missing[h]++
+			}
+		}
+		if missing[h] == 0 {
+			queue = append(queue, h)
+		}
+	}
+	sortHashes(queue)
+	order := make([]hash.Hash, 0, len(missing))
+	for len(queue) > 0 {
+		h := queue[0]
+		queue = queue[1:]
+		order = append(order, h)
+		cs := append([]hash.Hash{}, g.vertices[h].children...)
+		sortHashes(cs)
+		for _, c := range cs {
+			missing[c]--
+			if missing[c] == 0 {
+				queue = append(queue, c)
+			}
+		}
+	}
+	return order
+}
@@ -2,6 +2,8 @@ package hash
 
 import (
 	"encoding/base64"
+	"math/bits"
+
 	"github.com/deckarep/golang-set"
 	"golang.org/x/crypto/blake2b"
 )
@@ -23,7 +25,17 @@ func (h Hash) String() string {
 	return base64.URLEncoding.EncodeToString(h[:])
 }
 
-// Weight is the difficulty (or number of leading zeroes) of a site
+// Difficulty is the number of leading zero bits of the hash
+func (h Hash) Difficulty() int {
+	for i, b := range h {
+		if b != 0 {
+			return i*8 + bits.LeadingZeros8(b)
+		}
+	}
+	return HashSize * 8
+}
+
+// Weight is the number of leading zero bytes. Use Difficulty for a bit granular measure
 func (h Hash) Weight() int {
 	weight := 0
 	for _, b := range h {
@@ -6,6 +6,17 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestDifficulty(t *testing.T) {
+	assert.Equal(t, 7, Hash{1, 3, 3, 7}.Difficulty())
+	assert.Equal(t, 0, Hash{128}.Difficulty())
+	assert.Equal(t, 8, Hash{0, 255}.Difficulty())
+	assert.Equal(t, 13, Hash{0, 4, 0, 7}.Difficulty())
+	assert.Equal(t, 256, Hash{}.Difficulty())
+	for _, h := range []Hash{{0, 3, 3, 7}, {0, 0, 0, 0, 0, 5}, {1}} {
+		assert.True(t, h.Difficulty() >= 8*h.Weight())
+	}
+}
+
 func TestWeight(t *testing.T) {
 	assert.Equal(t, 0, Hash{1, 3, 3, 7}.Weight())
 	assert.Equal(t, 1, Hash{0, 3, 3, 7}.Weight())
@@ -0,0 +1,195 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/u-speak/core/tangle/hash"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// postingBucketName maps term, 0 and site hash to the encoded positions
+	postingBucketName = []byte("postings")
+	// termBucketName maps every term to the number of sites containing it
+	termBucketName = []byte("terms")
+	siteBucketName = []byte("sites")
+)
+
+// BoltIndex stores the index in a boltdb
+type BoltIndex struct {
+	db *bolt.DB
+}
+
+// NewBoltIndex opens or creates the index at path
+func NewBoltIndex(path string) (*BoltIndex, error) {
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		return nil, err
+	}
+	b := &BoltIndex{db: db}
+	err = db.Update(createBuckets)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func createBuckets(tx *bolt.Tx) error {
+	for _, n := range [][]byte{postingBucketName, termBucketName, siteBucketName} {
+		_, err := tx.CreateBucketIfNotExists(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add indexes the text of the site
+func (b *BoltIndex) Add(h hash.Hash, text string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		sites := tx.Bucket(siteBucketName)
+		if sites.Get(h.Slice()) != nil {
+			return nil
+		}
+		err := sites.Put(h.Slice(), []byte{})
+		if err != nil {
+			return err
+		}
+		terms := tx.Bucket(termBucketName)
+		for t, ps := range positionsOf(text) {
+			err = tx.Bucket(postingBucketName).Put(postingKey(t, h), encodePositions(ps))
+			if err != nil {
+				return err
+			}
+			c := make([]byte, 8)
+			if v := terms.Get([]byte(t)); v != nil {
+				binary.BigEndian.PutUint64(c, binary.BigEndian.Uint64(v)+1)
+			} else {
+				binary.BigEndian.PutUint64(c, 1)
+			}
+			err = terms.Put([]byte(t), c)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Search returns the sites matching the query
+func (b *BoltIndex) Search(query string) []hash.Hash {
+	var res []hash.Hash
+	err := b.db.View(func(tx *bolt.Tx) error {
+		res = search(boltPostings{tx}, query)
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return res
+}
+
+// Count returns the number of sites containing the word
+func (b *BoltIndex) Count(word string) int {
+	n := 0
+	err := b.db.View(func(tx *bolt.Tx) error {
+		n = boltPostings{tx}.count(word)
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return n
+}
+
+// Size returns the number of indexed sites
+func (b *BoltIndex) Size() int {
+	n := 0
+	err := b.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(siteBucketName).Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+	}
+	return n
+}
+
+// Reset removes all entries
+func (b *BoltIndex) Reset() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, n := range [][]byte{postingBucketName, termBucketName, siteBucketName} {
+			err := tx.DeleteBucket(n)
+			if err != nil {
+				return err
+			}
+		}
+		return createBuckets(tx)
+	})
+}
+
+// Close releases the lock on the db
+func (b *BoltIndex) Close() {
+	err := b.db.Close()
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+type boltPostings struct {
+	tx *bolt.Tx
+}
+
+func (p boltPostings) count(term string) int {
+	v := p.tx.Bucket(termBucketName).Get([]byte(term))
+	if v == nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(v))
+}
+
+func (p boltPostings) sites(term string) []hash.Hash {
+	hs := []hash.Hash{}
+	prefix := append([]byte(term), 0)
+	c := p.tx.Bucket(postingBucketName).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		hs = append(hs, hash.FromSlice(k[len(prefix):]))
+	}
+	return hs
+}
+
+func (p boltPostings) positions(term string, h hash.Hash) []int {
+	return decodePositions(p.tx.Bucket(postingBucketName).Get(postingKey(term, h)))
+}
+
+func postingKey(term string, h hash.Hash) []byte {
+	k := make([]byte, 0, len(term)+1+hash.HashSize)
+	k = append(k, term...)
+	k = append(k, 0)
+	return append(k, h.Slice()...)
+}
+
+func encodePositions(ps []int) []byte {
+	buf := make([]byte, 0, len(ps)*2)
+	b := make([]byte, binary.MaxVarintLen64)
+	for _, p := range ps {
+		buf = append(buf, b[:binary.PutUvarint(b, uint64(p))]...)
+	}
+	return buf
+}
+
+func decodePositions(b []byte) []int {
+	ps := []int{}
+	for len(b) > 0 {
+		p, n := binary.Uvarint(b)
+		if n <= 0 {
+			break
+		}
+		ps = append(ps, int(p))
+		b = b[n:]
+	}
+	return ps
+}
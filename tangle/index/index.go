@@ -0,0 +1,127 @@
+package index
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/u-speak/core/tangle/hash"
+)
+
+// Index maps words to the sites containing them
+type Index interface {
+	// Add indexes the text of a site. Adding a site twice has no effect
+	Add(h hash.Hash, text string) error
+	// Search returns the sites containing all words and quoted phrases of the query, sorted by hash
+	Search(query string) []hash.Hash
+	// Count returns the number of sites containing the word
+	Count(word string) int
+	// Size returns the number of indexed sites
+	Size() int
+	// Reset removes all entries
+	Reset() error
+	Close()
+}
+
+// postings is implemented by the storage backends of an index
+type postings interface {
+	// count returns the number of sites containing the term
+	count(term string) int
+	// sites returns all sites containing the term
+	sites(term string) []hash.Hash
+	// positions returns the positions of the term inside the site
+	positions(term string, h hash.Hash) []int
+}
+
+// Tokenize splits the text into lower case words, ignoring punctuation
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// positionsOf maps every token of the text to its positions
+func positionsOf(text string) map[string][]int {
+	ps := make(map[string][]int)
+	for i, t := range Tokenize(text) {
+		ps[t] = append(ps[t], i)
+	}
+	return ps
+}
+
+// parse splits the query into phrases. Words outside of quotes are phrases of a single word
+func parse(query string) [][]string {
+	phrases := [][]string{}
+	for i, part := range strings.Split(query, "\"") {
+		ts := Tokenize(part)
+		if i%2 == 1 {
+			if len(ts) > 0 {
+				phrases = append(phrases, ts)
+			}
+			continue
+		}
+		for _, t := range ts {
+			phrases = append(phrases, []string{t})
+		}
+	}
+	return phrases
+}
+
+// search looks up the rarest term of the query and checks the remaining phrases for each of its sites
+func search(p postings, query string) []hash.Hash {
+	res := []hash.Hash{}
+	phrases := parse(query)
+	if len(phrases) == 0 {
+		return res
+	}
+	rarest, min := "", -1
+	for _, ph := range phrases {
+		for _, t := range ph {
+			c := p.count(t)
+			if c == 0 {
+				return res
+			}
+			if min < 0 || c < min {
+				rarest, min = t, c
+			}
+		}
+	}
+	for _, h := range p.sites(rarest) {
+		if matchAll(p, h, phrases) {
+			res = append(res, h)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return bytes.Compare(res[i][:], res[j][:]) < 0
+	})
+	return res
+}
+
+func matchAll(p postings, h hash.Hash, phrases [][]string) bool {
+	for _, ph := range phrases {
+		if !matchPhrase(p, h, ph) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPhrase checks whether the terms appear in consecutive positions
+func matchPhrase(p postings, h hash.Hash, phrase []string) bool {
+	starts := p.positions(phrase[0], h)
+	for i := 1; i < len(phrase) && len(starts) > 0; i++ {
+		next := make(map[int]bool)
+		for _, pos := range p.positions(phrase[i], h) {
+			next[pos] = true
+		}
+		s := starts[:0]
+		for _, pos := range starts {
+			if next[pos+i] {
+				s = append(s, pos)
+			}
+		}
+		starts = s
+	}
+	return len(starts) > 0
+}
@@ -0,0 +1,63 @@
+package index
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/hash"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"hello", "wörld", "it", "s", "2018"}, Tokenize("Hello, WÖRLD! It's 2018..."))
+	assert.Empty(t, Tokenize(" ,.- "))
+}
+
+func TestParse(t *testing.T) {
+	assert.Equal(t, [][]string{{"foo"}, {"bar", "baz"}, {"qux"}}, parse(`Foo "bar  BAZ" qux`))
+	assert.Equal(t, [][]string{{"foo"}, {"bar"}}, parse(`foo "bar`))
+	assert.Empty(t, parse(`"" ,`))
+}
+
+func testIndex(t *testing.T, idx Index) {
+	a, b, c := hash.Hash{1}, hash.Hash{2}, hash.Hash{3}
+	assert.NoError(t, idx.Add(a, "The quick brown fox jumps over the lazy dog"))
+	assert.NoError(t, idx.Add(b, "A brown dog. Quick!"))
+	assert.NoError(t, idx.Add(c, "Nothing to see here"))
+	assert.NoError(t, idx.Add(c, "Nothing to see here"))
+
+	assert.Equal(t, []hash.Hash{a, b}, idx.Search("BROWN"))
+	assert.Equal(t, []hash.Hash{a, b}, idx.Search("dog quick"))
+	assert.Equal(t, []hash.Hash{a}, idx.Search(`"quick brown"`))
+	assert.Equal(t, []hash.Hash{a}, idx.Search(`"lazy dog" the`))
+	assert.Equal(t, []hash.Hash{b}, idx.Search(`"brown dog"`))
+	assert.Empty(t, idx.Search(`"dog brown"`))
+	assert.Empty(t, idx.Search("cat"))
+	assert.Empty(t, idx.Search(""))
+	assert.Equal(t, []hash.Hash{c}, idx.Search("see"))
+	assert.Equal(t, 2, idx.Count("brown"))
+	assert.Equal(t, 0, idx.Count("cat"))
+	assert.Equal(t, 3, idx.Size())
+
+	assert.NoError(t, idx.Reset())
+	assert.Empty(t, idx.Search("brown"))
+	assert.Equal(t, 0, idx.Size())
+	assert.NoError(t, idx.Add(a, "brown"))
+	assert.Equal(t, []hash.Hash{a}, idx.Search("brown"))
+}
+
+func TestMemoryIndex(t *testing.T) {
+	idx := NewMemoryIndex()
+	defer idx.Close()
+	testIndex(t, idx)
+}
+
+func TestBoltIndex(t *testing.T) {
+	dbpath := path.Join(os.TempDir(), "testboltindex.db")
+	defer os.Remove(dbpath)
+	idx, err := NewBoltIndex(dbpath)
+	assert.NoError(t, err)
+	defer idx.Close()
+	testIndex(t, idx)
+}
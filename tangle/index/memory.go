@@ -0,0 +1,92 @@
+package index
+
+import (
+	"sync"
+
+	"github.com/u-speak/core/tangle/hash"
+)
+
+// MemoryIndex keeps the index in memory. It is safe for concurrent use.
+type MemoryIndex struct {
+	mu    sync.RWMutex
+	terms map[string]map[hash.Hash][]int
+	sites map[hash.Hash]bool
+}
+
+// NewMemoryIndex returns an empty index
+func NewMemoryIndex() *MemoryIndex {
+	m := &MemoryIndex{}
+	_ = m.Reset()
+	return m
+}
+
+// Add indexes the text of the site
+func (m *MemoryIndex) Add(h hash.Hash, text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sites[h] {
+		return nil
+	}
+	m.sites[h] = true
+	for t, ps := range positionsOf(text) {
+		if m.terms[t] == nil {
+			m.terms[t] = make(map[hash.Hash][]int)
+		}
+		m.terms[t][h] = ps
+	}
+	return nil
+}
+
+// Search returns the sites matching the query
+func (m *MemoryIndex) Search(query string) []hash.Hash {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return search(memoryPostings{m}, query)
+}
+
+// Count returns the number of sites containing the word
+func (m *MemoryIndex) Count(word string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.terms[word])
+}
+
+// Size returns the number of indexed sites
+func (m *MemoryIndex) Size() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.sites)
+}
+
+// Reset removes all entries
+func (m *MemoryIndex) Reset() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.terms = make(map[string]map[hash.Hash][]int)
+	m.sites = make(map[hash.Hash]bool)
+	return nil
+}
+
+// Close does nothing
+func (m *MemoryIndex) Close() {}
+
+// memoryPostings expects the caller to hold a lock
+type memoryPostings struct {
+	m *MemoryIndex
+}
+
+func (p memoryPostings) count(term string) int {
+	return len(p.m.terms[term])
+}
+
+func (p memoryPostings) sites(term string) []hash.Hash {
+	hs := make([]hash.Hash, 0, len(p.m.terms[term]))
+	for h := range p.m.terms[term] {
+		hs = append(hs, h)
+	}
+	return hs
+}
+
+func (p memoryPostings) positions(term string, h hash.Hash) []int {
+	return append([]int{}, p.m.terms[term][h]...)
+}
@@ -0,0 +1,51 @@
+package tangle
+
+import (
+	"os"
+
+	"github.com/u-speak/core/tangle/datastore"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// migrationBatchSize limits how many payloads are moved in a single transaction
+const migrationBatchSize = 1000
+
+// migrateData moves the payloads of the standalone payload database at path into the store.
+// Afterwards the database is renamed by appending ".migrated", so it is kept but not read again.
+// Nothing happens if there is no database at path.
+func migrateData(s store.Store, path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	ds, err := datastore.New(path)
+	if err != nil {
+		return err
+	}
+	n := 0
+	batch := make(map[hash.Hash][]byte)
+	flush := func() error {
+		err := s.PutPayloads(batch)
+		n += len(batch)
+		batch = make(map[hash.Hash][]byte)
+		return err
+	}
+	err = ds.ForEach(func(h hash.Hash, v []byte) error {
+		batch[h] = append([]byte{}, v...)
+		if len(batch) < migrationBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	ds.Close()
+	if err != nil {
+		return err
+	}
+	log.Infof("Moved %d payloads from %s into the store", n, path)
+	return os.Rename(path, path+".migrated")
+}
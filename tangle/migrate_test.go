@@ -0,0 +1,55 @@
+package tangle
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/datastore"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+)
+
+func TestMigrateData(t *testing.T) {
+	datapath := path.Join(os.TempDir(), "testmigratedata.db")
+	defer os.Remove(datapath)
+	defer os.Remove(datapath + ".migrated")
+	ds, err := datastore.New(datapath)
+	assert.NoError(t, err)
+	d := dd("legacy")
+	assert.NoError(t, ds.Put(d))
+	ds.Close()
+
+	// Sites of older versions are stored without their payload
+	st := ms()
+	tngl, err := New(Options{Store: st})
+	assert.NoError(t, err)
+	gen := tngl.tipHashes()
+	h, _ := d.Hash()
+	s := &site.Site{Content: h, Type: "dummy", Validates: gen}
+	s.Mine(MinimumDifficulty)
+	assert.NoError(t, st.Add(s, nil, []hash.Hash{s.Hash()}, gen))
+	_, err = tngl.Get(s.Hash())
+	assert.Equal(t, ErrCorrupt, err)
+	tngl.Close()
+
+	tngl, err = New(Options{Store: st, DataPath: datapath})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	o, err := tngl.Get(s.Hash())
+	if assert.NoError(t, err) {
+		assert.Equal(t, d, o.Data)
+	}
+	r, err := tngl.Check(false)
+	assert.NoError(t, err)
+	assert.True(t, r.OK())
+	_, err = os.Stat(datapath)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(datapath + ".migrated")
+	assert.NoError(t, err)
+
+	again, err := New(Options{Store: st, DataPath: datapath})
+	assert.NoError(t, err)
+	again.Close()
+}
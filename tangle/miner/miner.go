@@ -0,0 +1,141 @@
+package miner
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/pow"
+	"github.com/u-speak/core/tangle/site"
+)
+
+// DefaultInterval is the default time between two progress reports
+const DefaultInterval = time.Second
+
+// ErrExhausted is returned when no nonce up to the largest uint64 reaches the difficulty
+var ErrExhausted = errors.New("No nonce reaches the difficulty")
+
+// Options configure a mining run
+type Options struct {
+	// Workers is the number of goroutines searching nonces. Defaults to the number of CPUs
+	Workers int
+	// Progress is called periodically with the number of attempts and the current hash rate in hashes per second
+	Progress func(attempts uint64, rate float64)
+	// Interval is the time between two progress reports. Defaults to DefaultInterval
+	Interval time.Duration
+}
+
+// Result describes a successful mining run
+type Result struct {
+	Nonce    uint64
+	Hash     hash.Hash
+	Attempts uint64
+	Duration time.Duration
+}
+
+// Rate returns the average hash rate in hashes per second
+func (r *Result) Rate() float64 {
+	return rate(r.Attempts, r.Duration)
+}
+
+func rate(attempts uint64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(attempts) / d.Seconds()
+}
+
+// Mine searches the smallest nonce starting at s.Nonce whose proof of work has at least difficulty leading zero bits,
+// using the algorithm recorded in the site.
+// The nonce space is interleaved across the workers, so the result is the same as the one of site.Site.Mine.
+// The constant parts of the hash input are computed once. On success the nonce of s is set.
+// Mine stops when ctx is done, returning its error and leaving s unchanged.
+func Mine(ctx context.Context, s *site.Site, difficulty int, o Options) (*Result, error) {
+	workers := o.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	interval := o.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	a, err := pow.Lookup(s.PoW)
+	if err != nil {
+		return nil, err
+	}
+	// Slow algorithms check for cancellation and report their attempts after every attempt
+	batch := uint64(1)
+	if b, ok := a.(pow.Batched); ok && b.Batch() > 1 {
+		batch = uint64(b.Batch())
+	}
+	prefix, suffix := s.HashInput()
+	start := time.Now()
+	best := uint64(math.MaxUint64)
+	var attempts uint64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(first uint64) {
+			defer wg.Done()
+			buf := make([]byte, 0, len(prefix)+20+len(suffix))
+			n, tried := first, uint64(0)
+			for n < atomic.LoadUint64(&best) {
+				tried++
+				if tried%batch == 0 {
+					atomic.AddUint64(&attempts, batch)
+					if ctx.Err() != nil {
+						return
+					}
+				}
+				buf = append(strconv.AppendUint(append(buf[:0], prefix...), n, 10), suffix...)
+				if a.Sum(buf).Difficulty() >= difficulty {
+					for {
+						b := atomic.LoadUint64(&best)
+						if n >= b || atomic.CompareAndSwapUint64(&best, b, n) {
+							break
+						}
+					}
+					break
+				}
+				if n > math.MaxUint64-uint64(workers) {
+					break
+				}
+				n += uint64(workers)
+			}
+			atomic.AddUint64(&attempts, tried%batch)
+		}(s.Nonce + uint64(i))
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			d := time.Since(start)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			n := atomic.LoadUint64(&best)
+			if n == math.MaxUint64 {
+				return nil, ErrExhausted
+			}
+			s.Nonce = n
+			return &Result{Nonce: n, Hash: s.Hash(), Attempts: atomic.LoadUint64(&attempts), Duration: d}, nil
+		case <-ticker.C:
+			if o.Progress != nil {
+				a := atomic.LoadUint64(&attempts)
+				o.Progress(a, rate(a, time.Since(start)))
+			}
+		}
+	}
+}
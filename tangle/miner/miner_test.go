@@ -0,0 +1,56 @@
+package miner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/pow"
+	"github.com/u-speak/core/tangle/site"
+)
+
+func TestMine(t *testing.T) {
+	for _, workers := range []int{1, 3, 8} {
+		expected := &site.Site{Content: hash.New([]byte("miner")), Type: "dummy", Validates: []hash.Hash{{1}, {2}}}
+		s := *expected
+		expected.Mine(12)
+
+		r, err := Mine(context.Background(), &s, 12, Options{Workers: workers})
+		assert.NoError(t, err)
+		assert.Equal(t, expected.Nonce, r.Nonce)
+		assert.Equal(t, expected.Nonce, s.Nonce)
+		assert.Equal(t, expected.Hash(), r.Hash)
+		assert.True(t, r.Hash.Difficulty() >= 12)
+		assert.True(t, r.Attempts > r.Nonce)
+	}
+}
+
+func TestMineCancel(t *testing.T) {
+	s := &site.Site{Content: hash.New([]byte("cancel"))}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	reports := 0
+	_, err := Mine(ctx, s, 8*hash.HashSize, Options{Interval: 10 * time.Millisecond, Progress: func(a uint64, r float64) {
+		reports++
+		assert.True(t, r >= 0)
+	}})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, uint64(0), s.Nonce)
+	assert.True(t, reports > 0)
+}
+
+func TestMineCancelArgon2(t *testing.T) {
+	s := &site.Site{Content: hash.New([]byte("cancel")), PoW: pow.Argon2Name}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var attempts uint64
+	start := time.Now()
+	_, err := Mine(ctx, s, 8*hash.HashSize, Options{Workers: 2, Interval: 10 * time.Millisecond, Progress: func(a uint64, r float64) {
+		attempts = a
+	}})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second, "cancelling took %s", time.Since(start))
+	assert.True(t, attempts > 0)
+}
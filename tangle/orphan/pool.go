@@ -0,0 +1,292 @@
+package orphan
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// DefaultMaxSize is the default limit of orphans kept in the pool
+	DefaultMaxSize = 10000
+	// DefaultMaxAge is the default time an orphan is kept waiting for its parents
+	DefaultMaxAge = 24 * time.Hour
+)
+
+var (
+	orphanBucketName  = []byte("orphans")
+	waitingBucketName = []byte("waiting")
+	ageBucketName     = []byte("age")
+
+	// ErrNotFound is returned when the requested orphan is not in the pool
+	ErrNotFound = errors.New("Orphan not found")
+)
+
+// Orphan is a received site whose parents are not known yet
+type Orphan struct {
+	Site *site.Site
+	// Data is the serialized payload
+	Data []byte
+	Tip  bool
+	// History is set for sites received through Splice, which may predate sites carrying a time
+	History bool
+	// Origin is the node the site was received from
+	Origin   string
+	Received time.Time
+}
+
+type record struct {
+	Site     []byte
+	Data     []byte
+	Tip      bool
+	History  bool
+	Origin   string
+	Received int64
+}
+
+// Options for the pool, used at initialization
+type Options struct {
+	Path string
+	// MaxSize limits the number of orphans. When the pool is full, the oldest orphan is dropped
+	MaxSize int
+	// MaxAge is the time after which Expire drops an orphan
+	MaxAge time.Duration
+}
+
+// Pool keeps orphans in a boltdb until they can be added to the tangle
+type Pool struct {
+	db      *bolt.DB
+	maxSize int
+	maxAge  time.Duration
+}
+
+// New opens or creates the pool
+func New(o Options) (*Pool, error) {
+	db, err := bolt.Open(o.Path, 0644, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, n := range [][]byte{orphanBucketName, waitingBucketName, ageBucketName} {
+			_, err := tx.CreateBucketIfNotExists(n)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	p := &Pool{db: db, maxSize: o.MaxSize, maxAge: o.MaxAge}
+	if p.maxSize <= 0 {
+		p.maxSize = DefaultMaxSize
+	}
+	if p.maxAge <= 0 {
+		p.maxAge = DefaultMaxAge
+	}
+	return p, nil
+}
+
+// Add stores the orphan, dropping the oldest orphans if the pool is full
+func (p *Pool) Add(o *Orphan) error {
+	h := o.Site.Hash()
+	b, err := msgpack.Marshal(&record{
+		Site:     o.Site.Serialize(),
+		Data:     o.Data,
+		Tip:      o.Tip,
+		History:  o.History,
+		Origin:   o.Origin,
+		Received: o.Received.UnixNano(),
+	})
+	if err != nil {
+		return err
+	}
+	return p.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(orphanBucketName).Get(h.Slice()) != nil {
+			return nil
+		}
+		c := tx.Bucket(ageBucketName).Cursor()
+		for n := tx.Bucket(orphanBucketName).Stats().KeyN; n >= p.maxSize; n-- {
+			k, _ := c.First()
+			if k == nil {
+				break
+			}
+			log.Warnf("Orphan pool full, dropping %s", hash.FromSlice(k[8:]))
+			err := remove(tx, hash.FromSlice(k[8:]))
+			if err != nil {
+				return err
+			}
+		}
+		err := tx.Bucket(orphanBucketName).Put(h.Slice(), b)
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(ageBucketName).Put(ageKey(o.Received, h), []byte{})
+		if err != nil {
+			return err
+		}
+		for _, v := range o.Site.Validates {
+			err = tx.Bucket(waitingBucketName).Put(append(v.Slice(), h.Slice()...), []byte{})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the orphan with the specified hash
+func (p *Pool) Get(h hash.Hash) (*Orphan, error) {
+	var o *Orphan
+	err := p.db.View(func(tx *bolt.Tx) error {
+		var err error
+		o, err = get(tx, h)
+		return err
+	})
+	return o, err
+}
+
+// Has checks whether the site is waiting in the pool
+func (p *Pool) Has(h hash.Hash) bool {
+	found := false
+	_ = p.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(orphanBucketName).Get(h.Slice()) != nil
+		return nil
+	})
+	return found
+}
+
+// Remove drops the orphan from the pool
+func (p *Pool) Remove(h hash.Hash) error {
+	return p.db.Update(func(tx *bolt.Tx) error {
+		return remove(tx, h)
+	})
+}
+
+// Waiting returns the hashes of all orphans validating the parent
+func (p *Pool) Waiting(parent hash.Hash) []hash.Hash {
+	hs := []hash.Hash{}
+	_ = p.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(waitingBucketName).Cursor()
+		prefix := parent.Slice()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			hs = append(hs, hash.FromSlice(k[hash.HashSize:]))
+		}
+		return nil
+	})
+	return hs
+}
+
+// Orphans returns the hashes of all orphans, oldest first
+func (p *Pool) Orphans() []hash.Hash {
+	hs := []hash.Hash{}
+	_ = p.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(ageBucketName).ForEach(func(k, _ []byte) error {
+			hs = append(hs, hash.FromSlice(k[8:]))
+			return nil
+		})
+	})
+	return hs
+}
+
+// Expire drops all orphans received more than MaxAge before now and returns how many were dropped
+func (p *Pool) Expire(now time.Time) (int, error) {
+	n := 0
+	limit := ageKey(now.Add(-p.maxAge), hash.Hash{})
+	err := p.db.Update(func(tx *bolt.Tx) error {
+		expired := []hash.Hash{}
+		c := tx.Bucket(ageBucketName).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, limit) < 0; k, _ = c.Next() {
+			expired = append(expired, hash.FromSlice(k[8:]))
+		}
+		for _, h := range expired {
+			err := remove(tx, h)
+			if err != nil {
+				return err
+			}
+		}
+		n = len(expired)
+		return nil
+	})
+	return n, err
+}
+
+// Size returns the number of orphans in the pool
+func (p *Pool) Size() int {
+	var n int
+	_ = p.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(orphanBucketName).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// Close releases the lock on the db
+func (p *Pool) Close() {
+	err := p.db.Close()
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func get(tx *bolt.Tx, h hash.Hash) (*Orphan, error) {
+	b := tx.Bucket(orphanBucketName).Get(h.Slice())
+	if b == nil {
+		return nil, ErrNotFound
+	}
+	r := record{}
+	err := msgpack.Unmarshal(b, &r)
+	if err != nil {
+		return nil, err
+	}
+	s := &site.Site{}
+	err = s.Deserialize(r.Site)
+	if err != nil {
+		return nil, err
+	}
+	return &Orphan{
+		Site:     s,
+		Data:     r.Data,
+		Tip:      r.Tip,
+		History:  r.History,
+		Origin:   r.Origin,
+		Received: time.Unix(0, r.Received),
+	}, nil
+}
+
+func remove(tx *bolt.Tx, h hash.Hash) error {
+	o, err := get(tx, h)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, v := range o.Site.Validates {
+		err = tx.Bucket(waitingBucketName).Delete(append(v.Slice(), h.Slice()...))
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Bucket(ageBucketName).Delete(ageKey(o.Received, h))
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(orphanBucketName).Delete(h.Slice())
+}
+
+// ageKey sorts orphans by the time they were received
+func ageKey(t time.Time, h hash.Hash) []byte {
+	k := make([]byte, 8, 8+hash.HashSize)
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	return append(k, h.Slice()...)
+}
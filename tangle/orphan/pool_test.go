@@ -0,0 +1,82 @@
+package orphan
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+)
+
+func orphan(c byte, received time.Time, parents ...hash.Hash) *Orphan {
+	return &Orphan{
+		Site:     &site.Site{Content: hash.Hash{c}, Validates: parents},
+		Data:     []byte{c},
+		Origin:   "127.0.0.1:6969",
+		Received: received,
+	}
+}
+
+func TestAddGet(t *testing.T) {
+	dbpath := path.Join(os.TempDir(), "testorphanaddget.db")
+	defer os.Remove(dbpath)
+	p, err := New(Options{Path: dbpath})
+	assert.NoError(t, err)
+	defer p.Close()
+
+	now := time.Now()
+	missing := hash.Hash{42}
+	o1 := orphan(1, now, missing, hash.Hash{43})
+	o2 := orphan(2, now, missing, o1.Site.Hash())
+	o1.History = true
+	assert.NoError(t, p.Add(o1))
+	assert.NoError(t, p.Add(o2))
+	assert.NoError(t, p.Add(o2))
+	assert.Equal(t, 2, p.Size())
+	assert.True(t, p.Has(o1.Site.Hash()))
+
+	g, err := p.Get(o1.Site.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, o1.Site, g.Site)
+	assert.Equal(t, o1.Data, g.Data)
+	assert.Equal(t, o1.Origin, g.Origin)
+	assert.True(t, g.History)
+	assert.True(t, o1.Received.Equal(g.Received))
+
+	assert.ElementsMatch(t, []hash.Hash{o1.Site.Hash(), o2.Site.Hash()}, p.Waiting(missing))
+	assert.Equal(t, []hash.Hash{o2.Site.Hash()}, p.Waiting(o1.Site.Hash()))
+
+	assert.NoError(t, p.Remove(o1.Site.Hash()))
+	assert.False(t, p.Has(o1.Site.Hash()))
+	_, err = p.Get(o1.Site.Hash())
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, []hash.Hash{o2.Site.Hash()}, p.Waiting(missing))
+}
+
+func TestLimits(t *testing.T) {
+	dbpath := path.Join(os.TempDir(), "testorphanlimits.db")
+	defer os.Remove(dbpath)
+	p, err := New(Options{Path: dbpath, MaxSize: 3, MaxAge: time.Hour})
+	assert.NoError(t, err)
+	defer p.Close()
+
+	now := time.Now()
+	orphans := []*Orphan{}
+	for i := 0; i < 4; i++ {
+		o := orphan(byte(i), now.Add(time.Duration(i)*time.Minute), hash.Hash{42})
+		orphans = append(orphans, o)
+		assert.NoError(t, p.Add(o))
+	}
+	assert.Equal(t, 3, p.Size())
+	assert.False(t, p.Has(orphans[0].Site.Hash()))
+	assert.Equal(t, []hash.Hash{orphans[1].Site.Hash(), orphans[2].Site.Hash(), orphans[3].Site.Hash()}, p.Orphans())
+
+	n, err := p.Expire(now.Add(time.Hour + 2*time.Minute + time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []hash.Hash{orphans[3].Site.Hash()}, p.Orphans())
+	assert.Equal(t, []hash.Hash{orphans[3].Site.Hash()}, p.Waiting(hash.Hash{42}))
+}
@@ -0,0 +1,131 @@
+package pow
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/u-speak/core/tangle/hash"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// Default is the algorithm of sites without a recorded algorithm
+	Default = "blake2b"
+	// Argon2Name is the name of the memory hard algorithm using DefaultArgon2
+	Argon2Name = "argon2id"
+)
+
+var (
+	// ErrUnknownAlgorithm is returned when an algorithm has not been registered
+	ErrUnknownAlgorithm = errors.New("Proof of work algorithm not registered")
+
+	// DefaultArgon2 is the configuration registered as Argon2Name.
+	// Every attempt needs 16MiB of memory, which takes away most of the advantage of GPUs.
+	DefaultArgon2 = Argon2{Time: 1, Memory: 16 * 1024, Threads: 1}
+
+	argon2Salt = []byte("u-speak proof of work")
+
+	registryMu sync.RWMutex
+	registry   = make(map[string]Algorithm)
+)
+
+// Algorithm computes the proof of work of a site. The difficulty is the number of leading zero bits of its digest
+type Algorithm interface {
+	// Name is recorded in the sites mined with the algorithm
+	Name() string
+	// Sum returns the digest of the hash input of a site
+	Sum(input []byte) hash.Hash
+}
+
+// Batched is implemented by algorithms fast enough to run several attempts between two checks for cancellation
+type Batched interface {
+	// Batch returns the number of attempts a miner may run before checking whether it was cancelled
+	Batch() int
+}
+
+func init() {
+	Register(Blake2b{})
+	Register(DefaultArgon2)
+}
+
+// Register makes an algorithm available under its name.
+// It panics when the name is empty or has already been registered.
+func Register(a Algorithm) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if a.Name() == "" {
+		panic("pow: Register called with unnamed algorithm")
+	}
+	if _, dup := registry[a.Name()]; dup {
+		panic("pow: Register called twice for algorithm " + a.Name())
+	}
+	registry[a.Name()] = a
+}
+
+// Lookup returns the registered algorithm. The empty name returns the Default algorithm
+func Lookup(name string) (Algorithm, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	a, ok := registry[Name(name)]
+	if !ok {
+		return nil, ErrUnknownAlgorithm
+	}
+	return a, nil
+}
+
+// Name normalizes the name recorded in a site, mapping the empty name to Default
+func Name(name string) string {
+	if name == "" {
+		return Default
+	}
+	return name
+}
+
+// Algorithms returns the sorted names of all registered algorithms
+func Algorithms() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	ns := []string{}
+	for n := range registry {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return ns
+}
+
+// Blake2b uses the site hash itself as proof of work
+type Blake2b struct{}
+
+// Name returns Default
+func (Blake2b) Name() string {
+	return Default
+}
+
+// Sum returns the blake2b-256 digest of the input
+func (Blake2b) Sum(input []byte) hash.Hash {
+	return hash.New(input)
+}
+
+// Batch returns 1024, a single blake2b attempt takes well below a microsecond
+func (Blake2b) Batch() int {
+	return 1024
+}
+
+// Argon2 is a memory hard proof of work using argon2id
+type Argon2 struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+// Name returns Argon2Name
+func (Argon2) Name() string {
+	return Argon2Name
+}
+
+// Sum returns the argon2id key derived from the input
+func (a Argon2) Sum(input []byte) hash.Hash {
+	return hash.FromSlice(argon2.IDKey(input, argon2Salt, a.Time, a.Memory, a.Threads, hash.HashSize))
+}
@@ -0,0 +1,30 @@
+package pow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/hash"
+)
+
+func TestLookup(t *testing.T) {
+	a, err := Lookup("")
+	assert.NoError(t, err)
+	assert.Equal(t, Default, a.Name())
+	a, err = Lookup(Argon2Name)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultArgon2, a)
+	_, err = Lookup("sha256")
+	assert.Equal(t, ErrUnknownAlgorithm, err)
+	assert.Equal(t, []string{Argon2Name, Default}, Algorithms())
+	assert.Panics(t, func() { Register(Blake2b{}) })
+}
+
+func TestSum(t *testing.T) {
+	in := []byte("proof of work")
+	assert.Equal(t, hash.New(in), Blake2b{}.Sum(in))
+	a := Argon2{Time: 1, Memory: 64, Threads: 1}
+	assert.Equal(t, a.Sum(in), a.Sum(in))
+	assert.NotEqual(t, a.Sum(in), a.Sum([]byte("other")))
+	assert.NotEqual(t, hash.New(in), a.Sum(in))
+}
@@ -0,0 +1,120 @@
+package tangle
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/u-speak/core/tangle/datastore"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/query"
+	"github.com/u-speak/core/tangle/site"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Result is a site matching a query
+type Result struct {
+	*Object
+	// Score is the relevance of the site for the words of the query
+	Score float64
+}
+
+// Query returns all sites matching the structured query, sorted by the given order.
+// Queries containing required words are answered from the search index, others scan all sites.
+// Results are scored by the frequency of the query terms, weighted by how rare they are in the index.
+func (t *Tangle) Query(q *query.Query, order query.Order) []Result {
+	var hs []hash.Hash
+	if ws := q.Words(); len(ws) > 0 {
+		hs = t.index.Search(strings.Join(ws, " "))
+	} else {
+		hs = []hash.Hash{}
+		err := t.store.ForEach(func(h hash.Hash, s *site.Site) bool {
+			if s == nil {
+				return true
+			}
+			if typ, err := datastore.Lookup(s.Type); err == nil && !typ.Virtual {
+				hs = append(hs, h)
+			}
+			return true
+		})
+		if err != nil {
+			log.Error(err)
+		}
+	}
+	size := float64(t.index.Size())
+	idf := make(map[string]float64)
+	weight := func(term string) float64 {
+		if w, ok := idf[term]; ok {
+			return w
+		}
+		idf[term] = math.Log(1 + (size+1)/(float64(t.index.Count(term))+1))
+		return idf[term]
+	}
+	type result struct {
+		Result
+		doc *query.Document
+		w   int
+	}
+	rs := []result{}
+	for _, h := range hs {
+		o, err := t.Get(h)
+		if err != nil {
+			continue
+		}
+		if typ, err := datastore.Lookup(o.Site.Type); err != nil || typ.Virtual {
+			continue
+		}
+		d := Document(o)
+		if !q.Match(d) {
+			continue
+		}
+		r := result{Result: Result{Object: o, Score: q.Score(d, weight)}, doc: d}
+		if order == query.ByWeight {
+			r.w = t.Weight(o.Site)
+		}
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		switch order {
+		case query.ByRelevance:
+			if rs[i].Score != rs[j].Score {
+				return rs[i].Score > rs[j].Score
+			}
+		case query.ByDate:
+			if !rs[i].doc.Time.Equal(rs[j].doc.Time) {
+				return rs[i].doc.Time.After(rs[j].doc.Time)
+			}
+		case query.ByWeight:
+			if rs[i].w != rs[j].w {
+				return rs[i].w > rs[j].w
+			}
+		}
+		hi, hj := rs[i].Site.Hash(), rs[j].Site.Hash()
+		return bytes.Compare(hi[:], hj[:]) < 0
+	})
+	results := make([]Result, len(rs))
+	for i, r := range rs {
+		results[i] = r.Result
+	}
+	return results
+}
+
+// Document exposes the searchable fields of the object through the optional datastore interfaces
+func Document(o *Object) *query.Document {
+	d := &query.Document{Type: o.Site.Type}
+	if a, ok := o.Data.(datastore.Authored); ok {
+		d.Author = a.Author()
+	}
+	if dt, ok := o.Data.(datastore.Dated); ok {
+		d.Time = dt.Date()
+	}
+	if i, ok := o.Data.(datastore.Indexable); ok {
+		d.Text = i.Text()
+	}
+	if m, ok := o.Data.(datastore.Described); ok {
+		d.Meta = m.Meta()
+	}
+	return d
+}
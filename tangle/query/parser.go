@@ -0,0 +1,206 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/u-speak/core/tangle/index"
+)
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokPhrase
+	tokOpen
+	tokClose
+)
+
+type token struct {
+	kind tokenKind
+	// field is set for field:value filters
+	field  string
+	value  string
+	negate bool
+}
+
+// lex splits the query into words, phrases, filters and parentheses
+func lex(s string) []token {
+	rs := []rune(s)
+	ts := []token{}
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			ts = append(ts, token{kind: tokOpen})
+			i++
+		case r == ')':
+			ts = append(ts, token{kind: tokClose})
+			i++
+		default:
+			if r == '-' && i+1 < len(rs) && rs[i+1] == '(' {
+				ts = append(ts, token{kind: tokWord, value: "NOT"})
+				i++
+				continue
+			}
+			t := token{kind: tokWord}
+			if r == '-' && i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) {
+				t.negate = true
+				i++
+			}
+			start := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) && rs[i] != '(' && rs[i] != ')' {
+				if rs[i] == '"' {
+					if i == start {
+						t.kind = tokPhrase
+					} else if rs[i-1] == ':' {
+						t.field = string(rs[start : i-1])
+					} else {
+						i++
+						continue
+					}
+					end := i + 1
+					for end < len(rs) && rs[end] != '"' {
+						end++
+					}
+					t.value = string(rs[i+1 : min(end, len(rs))])
+					i = end + 1
+					break
+				}
+				i++
+			}
+			if t.kind == tokWord && t.field == "" {
+				t.value = string(rs[start:min(i, len(rs))])
+				if c := strings.Index(t.value, ":"); c > 0 && c < len(t.value)-1 {
+					t.field, t.value = t.value[:c], t.value[c+1:]
+				}
+			}
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// parser is a recursive descent parser, binding NOT tighter than AND and AND tighter than OR
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() *token {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *parser) keyword(k string) bool {
+	t := p.peek()
+	return t != nil && t.kind == tokWord && t.field == "" && !t.negate && t.value == k
+}
+
+func (p *parser) or() (node, error) {
+	n, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	ns := or{n}
+	for p.keyword("OR") {
+		p.pos++
+		n, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, n)
+	}
+	if len(ns) == 1 {
+		return ns[0], nil
+	}
+	return ns, nil
+}
+
+func (p *parser) and() (node, error) {
+	ns := and{}
+	for {
+		t := p.peek()
+		if t == nil || t.kind == tokClose || p.keyword("OR") {
+			break
+		}
+		if p.keyword("AND") {
+			p.pos++
+			continue
+		}
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			ns = append(ns, n)
+		}
+	}
+	switch len(ns) {
+	case 0:
+		return nil, ErrSyntax
+	case 1:
+		return ns[0], nil
+	}
+	return ns, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.keyword("NOT") {
+		p.pos++
+		if p.peek() == nil {
+			return nil, ErrSyntax
+		}
+		n, err := p.unary()
+		if err != nil || n == nil {
+			return nil, ErrSyntax
+		}
+		return not{n}, nil
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokOpen:
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.peek(); c == nil || c.kind != tokClose {
+			return nil, ErrSyntax
+		}
+		p.pos++
+		return n, nil
+	case tokClose:
+		return nil, ErrSyntax
+	}
+	var n node
+	if t.field != "" {
+		f, err := filter(t.field, t.value)
+		if err != nil {
+			return nil, err
+		}
+		n = f
+	} else {
+		ws := index.Tokenize(t.value)
+		if len(ws) == 0 {
+			// Words without letters or numbers can never match
+			return nil, nil
+		}
+		n = text(ws)
+	}
+	if t.negate {
+		return not{n}, nil
+	}
+	return n, nil
+}
@@ -0,0 +1,296 @@
+package query
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/u-speak/core/tangle/index"
+)
+
+// Order specifies how query results are sorted
+type Order string
+
+const (
+	// ByRelevance sorts results by their score, falling back to the hash for a stable order
+	ByRelevance Order = ""
+	// ByDate sorts results newest first
+	ByDate Order = "date"
+	// ByWeight sorts results by their cumulative weight, heaviest first
+	ByWeight Order = "weight"
+)
+
+var (
+	// ErrSyntax is returned for malformed queries
+	ErrSyntax = errors.New("Invalid query syntax")
+	// ErrInvalidDate is returned when before: or after: can not be parsed
+	ErrInvalidDate = errors.New("Invalid date, use YYYY-MM-DD, RFC3339 or a unix timestamp")
+	// ErrUnknownOrder is returned for unsupported sort orders
+	ErrUnknownOrder = errors.New("Unknown sort order, use relevance, date or weight")
+)
+
+// ParseOrder converts the name of an order, accepting an empty string or relevance for ByRelevance
+func ParseOrder(s string) (Order, error) {
+	switch o := Order(strings.ToLower(s)); o {
+	case ByRelevance, ByDate, ByWeight:
+		return o, nil
+	case "relevance":
+		return ByRelevance, nil
+	}
+	return ByRelevance, ErrUnknownOrder
+}
+
+// Document exposes the fields of a site a query can match
+type Document struct {
+	Type   string
+	Author string
+	// Time is zero if the payload is not dated
+	Time time.Time
+	Text string
+	// Meta contains additional fields like the frontmatter of posts
+	Meta map[string][]string
+}
+
+// Query is a parsed search query.
+//
+// Words and quoted phrases match the text of a site, case insensitive.
+// Filters have the form field:value, with the fields author, type, before and after.
+// Any other field is matched against the metadata, e.g. title:"hello world" or tag:news, which also matches tags.
+// Terms are combined with AND, which may be omitted, OR and NOT or a leading minus, grouped by parentheses.
+type Query struct {
+	root node
+}
+
+// Parse parses the query. An empty query matches everything
+func Parse(s string) (*Query, error) {
+	p := &parser{tokens: lex(s)}
+	if len(p.tokens) == 0 {
+		return &Query{}, nil
+	}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, ErrSyntax
+	}
+	return &Query{root: n}, nil
+}
+
+// Match checks whether the document satisfies the query
+func (q *Query) Match(d *Document) bool {
+	if q.root == nil {
+		return true
+	}
+	return q.root.match(newDoc(d))
+}
+
+// Words returns words every matching document has to contain.
+// They can be used to narrow down the candidates using a full text index.
+func (q *Query) Words() []string {
+	ws := []string{}
+	var collect func(n node)
+	collect = func(n node) {
+		switch v := n.(type) {
+		case and:
+			for _, c := range v {
+				collect(c)
+			}
+		case text:
+			ws = append(ws, v...)
+		}
+	}
+	collect(q.root)
+	return ws
+}
+
+// Terms returns all words the query searches for, excluding negated ones
+func (q *Query) Terms() []string {
+	ws := []string{}
+	seen := make(map[string]bool)
+	var collect func(n node)
+	collect = func(n node) {
+		switch v := n.(type) {
+		case and:
+			for _, c := range v {
+				collect(c)
+			}
+		case or:
+			for _, c := range v {
+				collect(c)
+			}
+		case text:
+			for _, w := range v {
+				if !seen[w] {
+					seen[w] = true
+					ws = append(ws, w)
+				}
+			}
+		}
+	}
+	collect(q.root)
+	return ws
+}
+
+// Score rates how relevant the document is for the terms of the query.
+// Every occurrence of a term adds to the score with diminishing returns, scaled by the weight of the term,
+// usually its inverse document frequency. Documents without any of the terms score 0.
+func (q *Query) Score(d *Document, weight func(term string) float64) float64 {
+	terms := q.Terms()
+	if len(terms) == 0 {
+		return 0
+	}
+	tf := make(map[string]int)
+	for _, t := range index.Tokenize(d.Text) {
+		tf[t]++
+	}
+	score := 0.0
+	for _, t := range terms {
+		if n := float64(tf[t]); n > 0 {
+			score += weight(t) * n / (n + 1)
+		}
+	}
+	return score
+}
+
+// doc caches the tokenized text of a document
+type doc struct {
+	*Document
+	tokens []string
+}
+
+func newDoc(d *Document) *doc {
+	return &doc{Document: d, tokens: index.Tokenize(d.Text)}
+}
+
+type node interface {
+	match(d *doc) bool
+}
+
+type and []node
+
+func (a and) match(d *doc) bool {
+	for _, n := range a {
+		if !n.match(d) {
+			return false
+		}
+	}
+	return true
+}
+
+type or []node
+
+func (o or) match(d *doc) bool {
+	for _, n := range o {
+		if n.match(d) {
+			return true
+		}
+	}
+	return false
+}
+
+type not struct {
+	node
+}
+
+func (n not) match(d *doc) bool {
+	return !n.node.match(d)
+}
+
+// text matches a phrase of consecutive tokens
+type text []string
+
+func (t text) match(d *doc) bool {
+	for i := 0; i+len(t) <= len(d.tokens); i++ {
+		found := true
+		for j, w := range t {
+			if d.tokens[i+j] != w {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+type typeFilter string
+
+func (f typeFilter) match(d *doc) bool {
+	return strings.EqualFold(d.Type, string(f))
+}
+
+// authorFilter matches the full fingerprint or its end, allowing key ids
+type authorFilter string
+
+func (f authorFilter) match(d *doc) bool {
+	a := strings.ToUpper(d.Author)
+	return a != "" && strings.HasSuffix(a, strings.ToUpper(string(f)))
+}
+
+type dateFilter struct {
+	t      time.Time
+	before bool
+}
+
+func (f dateFilter) match(d *doc) bool {
+	if d.Time.IsZero() {
+		return false
+	}
+	if f.before {
+		return d.Time.Before(f.t)
+	}
+	return !d.Time.Before(f.t)
+}
+
+// metaFilter matches if any value of the fields contains the value, case insensitive
+type metaFilter struct {
+	fields []string
+	value  string
+}
+
+func (f metaFilter) match(d *doc) bool {
+	for _, field := range f.fields {
+		for _, v := range d.Meta[field] {
+			if strings.Contains(strings.ToLower(v), f.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if u, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(u, 0), nil
+	}
+	return time.Time{}, ErrInvalidDate
+}
+
+func filter(field, value string) (node, error) {
+	field = strings.ToLower(field)
+	switch field {
+	case "type":
+		return typeFilter(value), nil
+	case "author":
+		return authorFilter(value), nil
+	case "before", "after":
+		t, err := parseDate(value)
+		if err != nil {
+			return nil, err
+		}
+		return dateFilter{t: t, before: field == "before"}, nil
+	case "tag", "tags":
+		return metaFilter{fields: []string{"tag", "tags"}, value: strings.ToLower(value)}, nil
+	}
+	return metaFilter{fields: []string{field}, value: strings.ToLower(value)}, nil
+}
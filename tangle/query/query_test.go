@@ -0,0 +1,92 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for _, q := range []string{"(", "a OR", "NOT", "a)", "()", "before:yesterday"} {
+		_, err := Parse(q)
+		assert.Error(t, err, q)
+	}
+	q, err := Parse(`"hello world" -(foo OR bar) tag:news`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hello", "world"}, q.Words())
+	q, err = Parse("hello OR world")
+	assert.NoError(t, err)
+	assert.Empty(t, q.Words())
+	assert.Equal(t, []string{"hello", "world"}, q.Terms())
+	q, err = Parse("hello -world type:post hello")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, q.Terms())
+}
+
+func TestMatch(t *testing.T) {
+	d := &Document{
+		Type:   "post",
+		Author: "0123456789ABCDEF0123456789ABCDEF01234567",
+		Time:   time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC),
+		Text:   "---\ntitle: Hello World\n---\nThe quick brown fox",
+		Meta:   map[string][]string{"title": {"Hello World"}, "tags": {"news", "animals"}},
+	}
+	for qs, expected := range map[string]bool{
+		"":                                   true,
+		"quick fox":                          true,
+		`"quick fox"`:                        false,
+		`"QUICK brown"`:                      true,
+		"quick AND NOT fox":                  false,
+		"quick -fox":                         false,
+		"cat OR fox":                         true,
+		"(cat OR dog) fox":                   false,
+		"type:post":                          true,
+		"type:image":                         false,
+		"author:89abcdef01234567":            true,
+		"author:0123456789abcdef":            false,
+		"after:2018-01-01 before:2018-04-01": true,
+		"before:2018-03-01":                  false,
+		"after:2018-03-01T13:00:00Z":         false,
+		`title:"hello world"`:                true,
+		"tags:news":                          true,
+		"tags:sports OR title:hello":         true,
+		"-tags:animals":                      false,
+		"category:news":                      false,
+	} {
+		q, err := Parse(qs)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, q.Match(d), qs)
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	o, err := ParseOrder("Date")
+	assert.NoError(t, err)
+	assert.Equal(t, ByDate, o)
+	o, err = ParseOrder("")
+	assert.NoError(t, err)
+	assert.Equal(t, ByRelevance, o)
+	o, err = ParseOrder("relevance")
+	assert.NoError(t, err)
+	assert.Equal(t, ByRelevance, o)
+	_, err = ParseOrder("random")
+	assert.Equal(t, ErrUnknownOrder, err)
+}
+
+func TestScore(t *testing.T) {
+	one := func(string) float64 { return 1 }
+	q, err := Parse("fox OR dog")
+	assert.NoError(t, err)
+	once := q.Score(&Document{Text: "a fox"}, one)
+	twice := q.Score(&Document{Text: "a fox and another fox"}, one)
+	both := q.Score(&Document{Text: "a fox and a dog"}, one)
+	assert.Equal(t, 0.5, once)
+	assert.True(t, twice > once)
+	assert.True(t, both > twice)
+	assert.Equal(t, 0.0, q.Score(&Document{Text: "a cat"}, one))
+	assert.Equal(t, 2.0, q.Score(&Document{Text: "fox"}, func(string) float64 { return 4 }))
+	q, err = Parse("type:post")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, q.Score(&Document{Text: "fox"}, one))
+}
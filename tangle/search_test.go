@@ -0,0 +1,92 @@
+package tangle
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/index"
+	"github.com/u-speak/core/tangle/query"
+	"github.com/u-speak/core/tangle/site"
+)
+
+func TestSearch(t *testing.T) {
+	indexpath := path.Join(os.TempDir(), "testsearchindex.db")
+	defer os.Remove(indexpath)
+	idx, err := index.NewBoltIndex(indexpath)
+	assert.NoError(t, err)
+	st := ms()
+	tngl, err := New(Options{Store: st, Index: idx})
+	assert.NoError(t, err)
+
+	hs := []hash.Hash{}
+	parents := tngl.tipHashes()
+	for _, c := range []string{"Hello World", "world peace", "hello again"} {
+		d := dd(c)
+		ch, _ := d.Hash()
+		s := &site.Site{Content: ch, Type: "dummy", Validates: parents}
+		mine(tngl, s)
+		assert.NoError(t, tngl.Inject(&Object{Site: s, Data: d}, true))
+		hs = append(hs, s.Hash())
+		parents = []hash.Hash{s.Hash(), parents[0]}
+	}
+	hits := func(q string) []hash.Hash {
+		r := []hash.Hash{}
+		for _, o := range tngl.Search(q) {
+			r = append(r, o.Site.Hash())
+		}
+		return r
+	}
+	assert.ElementsMatch(t, []hash.Hash{hs[0], hs[1]}, hits("WORLD"))
+	assert.ElementsMatch(t, []hash.Hash{hs[0]}, hits(`"hello world"`))
+	assert.Empty(t, hits("goodbye"))
+
+	assert.NoError(t, idx.Reset())
+	assert.Empty(t, hits("world"))
+	assert.NoError(t, tngl.RebuildIndex())
+	assert.ElementsMatch(t, []hash.Hash{hs[0], hs[1]}, hits("world"))
+	assert.ElementsMatch(t, []hash.Hash{hs[0], hs[2]}, hits("hello"))
+	tngl.Close()
+}
+
+func TestQuery(t *testing.T) {
+	tngl, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer tngl.Close()
+
+	hs := []hash.Hash{}
+	parents := tngl.tipHashes()
+	for _, c := range []string{"Hello World", "world peace", "hello again"} {
+		d := dd(c)
+		ch, _ := d.Hash()
+		s := &site.Site{Content: ch, Type: "dummy", Validates: parents}
+		mine(tngl, s)
+		assert.NoError(t, tngl.Inject(&Object{Site: s, Data: d}, true))
+		hs = append(hs, s.Hash())
+		parents = []hash.Hash{s.Hash(), parents[0]}
+	}
+	hits := func(qs string, order query.Order) []hash.Hash {
+		q, err := query.Parse(qs)
+		assert.NoError(t, err)
+		r := []hash.Hash{}
+		for _, o := range tngl.Query(q, order) {
+			r = append(r, o.Site.Hash())
+		}
+		return r
+	}
+	assert.Equal(t, []hash.Hash{hs[0], hs[1], hs[2]}, hits("world OR again", query.ByWeight))
+	assert.Equal(t, []hash.Hash{hs[1]}, hits("-hello", query.ByWeight))
+	assert.ElementsMatch(t, []hash.Hash{hs[0], hs[2]}, hits("type:dummy NOT peace", query.ByRelevance))
+	assert.Empty(t, hits("type:post", query.ByRelevance))
+	assert.Len(t, hits("", query.ByRelevance), 3)
+
+	q, err := query.Parse("hello OR peace")
+	assert.NoError(t, err)
+	rs := tngl.Query(q, query.ByRelevance)
+	assert.Len(t, rs, 3)
+	assert.Equal(t, hs[1], rs[0].Site.Hash())
+	assert.True(t, rs[0].Score > rs[1].Score)
+	assert.Equal(t, rs[1].Score, rs[2].Score)
+}
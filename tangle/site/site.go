@@ -1,15 +1,42 @@
 package site
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/pow"
 	"github.com/vmihailenco/msgpack"
 )
 
+const (
+	// FormatVersion is the current version of the serialized site format
+	FormatVersion = 2
+)
+
+// ErrEmpty is returned when deserializing an empty slice
+var ErrEmpty = errors.New("No site data")
+
 // Site represents a single storage node inside the tangle
 type Site struct {
-	Validates []*Site
+	Validates []hash.Hash
+	Nonce     uint64
+	Content   hash.Hash
+	Type      string
+	// PoW is the name of the proof of work algorithm, empty for pow.Default
+	PoW string `msgpack:",omitempty"`
+	// Time is the creation time in unix seconds, zero for sites created before sites carried a time
+	Time int64 `msgpack:",omitempty"`
+}
+
+// Resolver looks up sites by their hash. It is implemented by store.Store
+type Resolver interface {
+	Get(hash.Hash) (*Site, error)
+}
+
+// legacySite is the format version 1 representation, embedding the whole ancestry
+type legacySite struct {
+	Validates []*legacySite
 	Nonce     uint64
 	Content   hash.Hash
 	Type      string
@@ -17,27 +44,100 @@ type Site struct {
 
 // Hash computes the hash of the site
 func (s *Site) Hash() hash.Hash {
-	ts := "C" + s.Content.String() + "N" + strconv.FormatUint(s.Nonce, 10) + "T" + s.Type
-	for _, s := range s.Validates {
-		ts += "V" + s.Hash().String()
+	prefix, suffix := s.HashInput()
+	return hash.New(append(strconv.AppendUint(prefix, s.Nonce, 10), suffix...))
+}
+
+// HashInput returns the constant parts of the hash input before and after the decimal nonce.
+// The proof of work algorithm and the time are only included when set, keeping the hashes of older sites.
+func (s *Site) HashInput() (prefix []byte, suffix []byte) {
+	prefix = []byte("C" + s.Content.String() + "N")
+	ts := "T" + s.Type
+	for _, v := range s.Validates {
+		ts += "V" + v.String()
 	}
-	return hash.New([]byte(ts))
+	if s.PoW != "" {
+		ts += "P" + s.PoW
+	}
+	if s.Time != 0 {
+		ts += "S" + strconv.FormatInt(s.Time, 10)
+	}
+	return prefix, []byte(ts)
+}
+
+// Work returns the proof of work digest computed with the recorded algorithm
+func (s *Site) Work() (hash.Hash, error) {
+	a, err := pow.Lookup(s.PoW)
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	prefix, suffix := s.HashInput()
+	return a.Sum(append(strconv.AppendUint(prefix, s.Nonce, 10), suffix...)), nil
+}
+
+// Parents resolves the validated sites. Sites the resolver fails to return are skipped
+func (s *Site) Parents(r Resolver) []*Site {
+	ps := []*Site{}
+	for _, v := range s.Validates {
+		p, err := r.Get(v)
+		if err == nil {
+			ps = append(ps, p)
+		}
+	}
+	return ps
 }
 
 // Serialize converts the site to a slice of bytes
 func (s *Site) Serialize() []byte {
 	b, _ := msgpack.Marshal(s)
-	return b
+	return append([]byte{FormatVersion}, b...)
 }
 
-// Deserialize restores the site from a slice of bytes
+// Deserialize restores the site from a slice of bytes.
+// Sites stored in the legacy format are converted to hash references.
 func (s *Site) Deserialize(b []byte) error {
-	return msgpack.Unmarshal(b, s)
+	if len(b) == 0 {
+		return ErrEmpty
+	}
+	if !Legacy(b) {
+		return msgpack.Unmarshal(b[1:], s)
+	}
+	l := legacySite{}
+	err := msgpack.Unmarshal(b, &l)
+	if err != nil {
+		return err
+	}
+	*s = *l.convert()
+	return nil
+}
+
+// Legacy checks whether the serialized site uses the version 1 format
+func Legacy(b []byte) bool {
+	return len(b) > 0 && b[0] != FormatVersion
 }
 
-// Mine the block for a specifig weight
-func (s *Site) Mine(targetWeight int) {
-	for s.Hash().Weight() < targetWeight {
+// Mine the block for a specific difficulty in leading zero bits using the recorded algorithm.
+// Sites with an unknown algorithm are left unchanged. Use the miner package to mine on all cores
+func (s *Site) Mine(difficulty int) {
+	a, err := pow.Lookup(s.PoW)
+	if err != nil {
+		return
+	}
+	prefix, suffix := s.HashInput()
+	buf := make([]byte, 0, len(prefix)+20+len(suffix))
+	for {
+		buf = append(strconv.AppendUint(append(buf[:0], prefix...), s.Nonce, 10), suffix...)
+		if a.Sum(buf).Difficulty() >= difficulty {
+			return
+		}
 		s.Nonce++
 	}
 }
+
+func (l *legacySite) convert() *Site {
+	s := &Site{Nonce: l.Nonce, Content: l.Content, Type: l.Type}
+	for _, v := range l.Validates {
+		s.Validates = append(s.Validates, v.convert().Hash())
+	}
+	return s
+}
@@ -5,13 +5,15 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/u-speak/core/tangle/hash"
+	"github.com/vmihailenco/msgpack"
 	"golang.org/x/crypto/blake2b"
 )
 
 var dummyContent = blake2b.Sum256([]byte{1, 3, 3, 7})
 
-var dummySite = Site{Content: dummyContent, Nonce: 0, Validates: []*Site{{Content: dummyContent, Nonce: 0}}}
-var complexSite = Site{Content: dummyContent, Nonce: 0, Validates: []*Site{&dummySite, {Content: dummyContent, Nonce: 0, Validates: []*Site{&dummySite}}}}
+var simpleSite = Site{Content: dummyContent, Nonce: 0}
+var dummySite = Site{Content: dummyContent, Nonce: 0, Validates: []hash.Hash{simpleSite.Hash()}}
+var complexSite = Site{Content: dummyContent, Nonce: 0, Validates: []hash.Hash{dummySite.Hash(), (&Site{Content: dummyContent, Nonce: 0, Validates: []hash.Hash{dummySite.Hash()}}).Hash()}}
 
 func TestHash(t *testing.T) {
 	// Testing single site
@@ -23,6 +25,68 @@ func TestHash(t *testing.T) {
 	assert.Equal(t, hash.Hash{0x8c, 0x98, 0xc5, 0x7d, 0xb8, 0x78, 0x76, 0x8c, 0xe8, 0xcf, 0xb, 0x2e, 0xfb, 0xfa, 0x9a, 0x69, 0xf, 0x6d, 0x77, 0xe5, 0x16, 0x9e, 0x29, 0xa6, 0x41, 0x44, 0x6a, 0x27, 0x74, 0x52, 0xae, 0x55}, dummySite.Hash())
 }
 
+func TestPoW(t *testing.T) {
+	s := &Site{Content: dummyContent, Validates: []hash.Hash{simpleSite.Hash()}}
+	w, err := s.Work()
+	assert.NoError(t, err)
+	assert.Equal(t, s.Hash(), w)
+	b := s.Serialize()
+	assert.NotContains(t, string(b), "PoW")
+
+	a := *s
+	a.PoW = "argon2id"
+	assert.NotEqual(t, s.Hash(), a.Hash())
+	a.Mine(3)
+	w, err = a.Work()
+	assert.NoError(t, err)
+	assert.True(t, w.Difficulty() >= 3)
+	assert.NotEqual(t, a.Hash(), w)
+	r := &Site{}
+	assert.NoError(t, r.Deserialize(a.Serialize()))
+	assert.Equal(t, a, *r)
+
+	a.PoW = "unknown"
+	_, err = a.Work()
+	assert.Error(t, err)
+	n := a.Nonce
+	a.Mine(3)
+	assert.Equal(t, n, a.Nonce)
+}
+
+func TestTime(t *testing.T) {
+	s := &Site{Content: dummyContent, Validates: []hash.Hash{simpleSite.Hash()}}
+	assert.NotContains(t, string(s.Serialize()), "Time")
+	a := *s
+	a.Time = 1500000000
+	assert.NotEqual(t, s.Hash(), a.Hash())
+	r := &Site{}
+	assert.NoError(t, r.Deserialize(a.Serialize()))
+	assert.Equal(t, a, *r)
+	assert.Equal(t, a.Hash(), r.Hash())
+}
+
+func TestSerialize(t *testing.T) {
+	b := complexSite.Serialize()
+	assert.False(t, Legacy(b))
+	s := &Site{}
+	assert.NoError(t, s.Deserialize(b))
+	assert.Equal(t, complexSite.Hash(), s.Hash())
+	assert.Equal(t, ErrEmpty, s.Deserialize(nil))
+}
+
+func TestDeserializeLegacy(t *testing.T) {
+	simple := &legacySite{Content: dummyContent}
+	nested := &legacySite{Content: dummyContent, Validates: []*legacySite{simple}}
+	b, err := msgpack.Marshal(&legacySite{Content: dummyContent, Validates: []*legacySite{nested, simple}, Type: "post"})
+	assert.NoError(t, err)
+	assert.True(t, Legacy(b))
+
+	s := &Site{}
+	assert.NoError(t, s.Deserialize(b))
+	assert.Equal(t, []hash.Hash{dummySite.Hash(), simpleSite.Hash()}, s.Validates)
+	assert.Equal(t, "post", s.Type)
+}
+
 func BenchmarkSimpleSite(b *testing.B) {
 	s := &Site{Content: dummyContent, Nonce: 0}
 	for i := 0; i < b.N; i++ {
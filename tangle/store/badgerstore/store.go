@@ -0,0 +1,396 @@
+package badgerstore
+
+import (
+	"encoding/binary"
+
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+	"github.com/u-speak/core/tangle/store"
+
+	"github.com/dgraph-io/badger"
+	log "github.com/sirupsen/logrus"
+)
+
+// Keys of all records share one keyspace and are distinguished by their first byte
+const (
+	sitePrefix       = 's'
+	payloadPrefix    = 'p'
+	tipPrefix        = 't'
+	approverPrefix   = 'a'
+	quarantinePrefix = 'q'
+	workPrefix       = 'w'
+)
+
+// BadgerStore stores its persistence data in a badger database (github.com/dgraph-io/badger).
+// Its log structured merge tree keeps writes cheap during ingest bursts.
+// The path of the options is a directory.
+type BadgerStore struct {
+	db *badger.DB
+}
+
+// New returns a fresh initialized store
+func New(o store.Options) (*BadgerStore, error) {
+	s := &BadgerStore{}
+	return s, s.Init(o)
+}
+
+// Init the store. Writes are synced before they are acknowledged,
+// and a value log left incomplete by a crash is truncated on open.
+func (b *BadgerStore) Init(o store.Options) error {
+	opts := badger.DefaultOptions(o.Path).WithTruncate(true).WithLogger(logger{})
+	db, err := badger.Open(opts)
+	if err != nil {
+		return err
+	}
+	b.db = db
+	return nil
+}
+
+func key(prefix byte, hs ...hash.Hash) []byte {
+	k := make([]byte, 1, 1+len(hs)*hash.HashSize)
+	k[0] = prefix
+	for _, h := range hs {
+		k = append(k, h.Slice()...)
+	}
+	return k
+}
+
+// Add stores the site and its payload, indexes it as approver of its parents and updates the tips in one transaction
+func (b *BadgerStore) Add(d *site.Site, payload []byte, add []hash.Hash, del []hash.Hash) error {
+	return b.AddMany([]store.Entry{{Site: d, Payload: payload}}, add, del)
+}
+
+// AddMany stores all entries and updates the tips in one transaction
+func (b *BadgerStore) AddMany(es []store.Entry, add []hash.Hash, del []hash.Hash) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		for _, e := range es {
+			err := putSite(txn, e.Site, e.Payload)
+			if err != nil {
+				return err
+			}
+		}
+		return setTips(txn, add, del)
+	})
+}
+
+func putSite(txn *badger.Txn, d *site.Site, payload []byte) error {
+	h := d.Hash()
+	err := txn.Set(key(sitePrefix, h), d.Serialize())
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		err = txn.Set(key(payloadPrefix, d.Content), payload)
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range d.Validates {
+		err = txn.Set(key(approverPrefix, p, h), []byte{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get retrieves the site from the database
+func (b *BadgerStore) Get(h hash.Hash) (*site.Site, error) {
+	s := site.Site{}
+	err := b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key(sitePrefix, h))
+		if err == badger.ErrKeyNotFound {
+			return store.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(v []byte) error {
+			if err := s.Deserialize(v); err != nil {
+				log.Errorf("Site %s: %s", h, err)
+				return store.ErrCorrupt
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Has checks whether the site is stored
+func (b *BadgerStore) Has(h hash.Hash) bool {
+	err := b.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(key(sitePrefix, h))
+		return err
+	})
+	return err == nil
+}
+
+// Delete removes the site, its approver relations and its tip
+func (b *BadgerStore) Delete(h hash.Hash) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		return remove(txn, h, false)
+	})
+}
+
+// Quarantine moves the raw site into the quarantine keyspace, keeping it for inspection.
+// Unreadable sites are moved as well.
+func (b *BadgerStore) Quarantine(h hash.Hash) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		return remove(txn, h, true)
+	})
+}
+
+// remove deletes the site with its approver relations and its tip, optionally keeping the raw site in quarantine
+func remove(txn *badger.Txn, h hash.Hash, quarantine bool) error {
+	err := txn.Delete(key(tipPrefix, h))
+	if err != nil {
+		return err
+	}
+	err = txn.Delete(key(workPrefix, h))
+	if err != nil {
+		return err
+	}
+	item, err := txn.Get(key(sitePrefix, h))
+	if err == badger.ErrKeyNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	v, err := item.ValueCopy(nil)
+	if err != nil {
+		return err
+	}
+	if quarantine {
+		err = txn.Set(key(quarantinePrefix, h), v)
+		if err != nil {
+			return err
+		}
+	}
+	s := site.Site{}
+	if s.Deserialize(v) == nil {
+		for _, p := range s.Validates {
+			err = txn.Delete(key(approverPrefix, p, h))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return txn.Delete(key(sitePrefix, h))
+}
+
+// ForEach walks the sites in the order of their hashes using an iterator of a single read transaction
+func (b *BadgerStore) ForEach(fn func(hash.Hash, *site.Site) bool) error {
+	return b.db.View(func(txn *badger.Txn) error {
+		prefix := []byte{sitePrefix}
+		it := txn.NewIterator(badger.IteratorOptions{PrefetchValues: true, PrefetchSize: 100, Prefix: prefix})
+		defer it.Close()
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			item := it.Item()
+			var s *site.Site
+			err := item.Value(func(v []byte) error {
+				d := site.Site{}
+				if d.Deserialize(v) == nil {
+					s = &d
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if !fn(hash.FromSlice(item.Key()[1:]), s) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// keys calls fn with the remainder of every key starting with prefix
+func (b *BadgerStore) keys(prefix []byte, fn func(k []byte)) {
+	err := b.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		defer it.Close()
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			fn(it.Item().Key()[len(prefix):])
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// Payload returns the serialized payload with the content hash h
+func (b *BadgerStore) Payload(h hash.Hash) ([]byte, error) {
+	var p []byte
+	err := b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key(payloadPrefix, h))
+		if err == badger.ErrKeyNotFound {
+			return store.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		p, err = item.ValueCopy(nil)
+		return err
+	})
+	return p, err
+}
+
+// PutPayloads stores the payloads. Payloads exceeding the size of a single transaction are split across several,
+// which is safe because payloads are only read through the sites referencing them.
+func (b *BadgerStore) PutPayloads(ps map[hash.Hash][]byte) error {
+	txn := b.db.NewTransaction(true)
+	defer func() { txn.Discard() }()
+	for h, p := range ps {
+		err := txn.Set(key(payloadPrefix, h), p)
+		if err == badger.ErrTxnTooBig {
+			err = txn.Commit()
+			if err != nil {
+				return err
+			}
+			txn = b.db.NewTransaction(true)
+			err = txn.Set(key(payloadPrefix, h), p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+// RecordWork stores the difficulties of stored sites as uvarints, split across transactions like PutPayloads
+func (b *BadgerStore) RecordWork(ws map[hash.Hash]int) error {
+	txn := b.db.NewTransaction(true)
+	defer func() { txn.Discard() }()
+	for h, w := range ws {
+		_, err := txn.Get(key(sitePrefix, h))
+		if err == badger.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		v := make([]byte, binary.MaxVarintLen64)
+		v = v[:binary.PutUvarint(v, uint64(w))]
+		err = txn.Set(key(workPrefix, h), v)
+		if err == badger.ErrTxnTooBig {
+			err = txn.Commit()
+			if err != nil {
+				return err
+			}
+			txn = b.db.NewTransaction(true)
+			err = txn.Set(key(workPrefix, h), v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+// RecordedWork reads all recorded difficulties, skipping unreadable entries
+func (b *BadgerStore) RecordedWork() (map[hash.Hash]int, error) {
+	ws := make(map[hash.Hash]int)
+	err := b.db.View(func(txn *badger.Txn) error {
+		prefix := []byte{workPrefix}
+		it := txn.NewIterator(badger.IteratorOptions{PrefetchValues: true, PrefetchSize: 100, Prefix: prefix})
+		defer it.Close()
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			item := it.Item()
+			err := item.Value(func(v []byte) error {
+				if w, n := binary.Uvarint(v); n > 0 {
+					ws[hash.FromSlice(item.Key()[1:])] = int(w)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return ws, err
+}
+
+// Approvers returns the hashes of all sites directly validating h
+func (b *BadgerStore) Approvers(h hash.Hash) []hash.Hash {
+	as := []hash.Hash{}
+	b.keys(key(approverPrefix, h), func(k []byte) {
+		as = append(as, hash.FromSlice(k))
+	})
+	return as
+}
+
+// SetTips applies the delta of tips
+func (b *BadgerStore) SetTips(add []hash.Hash, del []hash.Hash) {
+	err := b.db.Update(func(txn *badger.Txn) error {
+		return setTips(txn, add, del)
+	})
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func setTips(txn *badger.Txn, add []hash.Hash, del []hash.Hash) error {
+	for _, d := range del {
+		err := txn.Delete(key(tipPrefix, d))
+		if err != nil {
+			return err
+		}
+	}
+	for _, a := range add {
+		err := txn.Set(key(tipPrefix, a), []byte{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTips returns the saved tips
+func (b *BadgerStore) GetTips() []hash.Hash {
+	tips := []hash.Hash{}
+	b.keys([]byte{tipPrefix}, func(k []byte) {
+		tips = append(tips, hash.FromSlice(k))
+	})
+	return tips
+}
+
+// Hashes returns all stored hashes
+func (b *BadgerStore) Hashes() []hash.Hash {
+	hs := []hash.Hash{}
+	b.keys([]byte{sitePrefix}, func(k []byte) {
+		hs = append(hs, hash.FromSlice(k))
+	})
+	return hs
+}
+
+// Size returns the number of stored sites, counting the keys without reading values
+func (b *BadgerStore) Size() int {
+	n := 0
+	b.keys([]byte{sitePrefix}, func([]byte) {
+		n++
+	})
+	return n
+}
+
+// Close releases the lock on the database directory
+func (b *BadgerStore) Close() {
+	err := b.db.Close()
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// logger forwards the messages of badger to logrus, demoting its informational messages to debug level
+type logger struct{}
+
+func (logger) Errorf(f string, v ...interface{})   { log.Errorf(f, v...) }
+func (logger) Warningf(f string, v ...interface{}) { log.Warnf(f, v...) }
+func (logger) Infof(f string, v ...interface{})    { log.Debugf(f, v...) }
+func (logger) Debugf(f string, v ...interface{})   { log.Debugf(f, v...) }
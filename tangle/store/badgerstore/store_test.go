@@ -0,0 +1,72 @@
+package badgerstore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+	"github.com/u-speak/core/tangle/store"
+	"github.com/u-speak/core/tangle/store/storetest"
+
+	"github.com/dgraph-io/badger"
+	"github.com/stretchr/testify/assert"
+)
+
+func open(t *testing.T) (*BadgerStore, func()) {
+	dir, err := ioutil.TempDir("", "badgerstore")
+	assert.NoError(t, err)
+	s, err := New(store.Options{Path: dir})
+	assert.NoError(t, err)
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		return open(t)
+	})
+}
+
+func get(s *BadgerStore, h hash.Hash) *site.Site {
+	st, _ := s.Get(h)
+	return st
+}
+
+func payload(s *BadgerStore, h hash.Hash) []byte {
+	p, _ := s.Payload(h)
+	return p
+}
+
+func TestCorrupt(t *testing.T) {
+	s, cleanup := open(t)
+	defer cleanup()
+	defer s.Close()
+
+	h := hash.Hash{1}
+	assert.NoError(t, s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key(sitePrefix, h), []byte{site.FormatVersion, 0xc1})
+	}))
+	_, err := s.Get(h)
+	assert.Equal(t, store.ErrCorrupt, err)
+	_, err = s.Get(hash.Hash{2})
+	assert.Equal(t, store.ErrNotFound, err)
+}
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badgerstore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	s, err := New(store.Options{Path: dir})
+	assert.NoError(t, err)
+	site1 := &site.Site{Content: hash.Hash{1}}
+	assert.NoError(t, s.Add(site1, []byte("one"), []hash.Hash{site1.Hash()}, nil))
+	s.Close()
+
+	s, err = New(store.Options{Path: dir})
+	assert.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, site1, get(s, site1.Hash()))
+	assert.Equal(t, []byte("one"), payload(s, site1.Content))
+	assert.Equal(t, []hash.Hash{site1.Hash()}, s.GetTips())
+}
@@ -0,0 +1,107 @@
+package boltstore
+
+import (
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// schemaVersion is the current layout of the database
+	// 1: sites embed their whole ancestry
+	// 2: sites reference their parents by hash (site.FormatVersion 2)
+	// 3: approvers are indexed
+	schemaVersion = 3
+	// migrationBatchSize limits how many sites are rewritten in a single transaction
+	migrationBatchSize = 1000
+)
+
+// migrate upgrades databases created with an older schemaVersion
+func (b *BoltStore) migrate() error {
+	var version byte
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(metaBucketName).Get(versionKey)
+		if len(v) == 1 {
+			version = v[0]
+		}
+		return nil
+	})
+	if version >= schemaVersion {
+		return nil
+	}
+	if version < 2 {
+		n, err := b.batch(func(tx *bolt.Tx, k, v []byte) error {
+			if !site.Legacy(v) {
+				return nil
+			}
+			s := site.Site{}
+			err := s.Deserialize(v)
+			if err != nil {
+				return err
+			}
+			return tx.Bucket(dataBucketName).Put(k, s.Serialize())
+		})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Infof("Migrated %d sites to format version %d", n, site.FormatVersion)
+		}
+	}
+	if version < 3 {
+		n, err := b.batch(func(tx *bolt.Tx, k, v []byte) error {
+			s := site.Site{}
+			err := s.Deserialize(v)
+			if err != nil {
+				return err
+			}
+			return indexApprover(tx, hash.FromSlice(k), s.Validates)
+		})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Infof("Indexed approvers of %d sites", n)
+		}
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucketName).Put(versionKey, []byte{schemaVersion})
+	})
+}
+
+// batch calls fn for every stored site, using one transaction per migrationBatchSize sites.
+// Keys and values are copies and may be written back inside fn.
+func (b *BoltStore) batch(fn func(tx *bolt.Tx, k, v []byte) error) (int, error) {
+	n := 0
+	var next []byte
+	for done := false; !done; {
+		err := b.db.Update(func(tx *bolt.Tx) error {
+			c := tx.Bucket(dataBucketName).Cursor()
+			k, v := c.First()
+			if next != nil {
+				k, v = c.Seek(next)
+			}
+			keys, vals := [][]byte{}, [][]byte{}
+			for ; k != nil && len(keys) < migrationBatchSize; k, v = c.Next() {
+				keys = append(keys, append([]byte{}, k...))
+				vals = append(vals, append([]byte{}, v...))
+			}
+			done = k == nil
+			next = append([]byte{}, k...)
+			for i := range keys {
+				err := fn(tx, keys[i], vals[i])
+				if err != nil {
+					return err
+				}
+			}
+			n += len(keys)
+			return nil
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
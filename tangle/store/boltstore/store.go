@@ -1,20 +1,29 @@
 package boltstore
 
 import (
+	"bytes"
+	"encoding/binary"
+
 	"github.com/u-speak/core/tangle/hash"
 	"github.com/u-speak/core/tangle/site"
 	"github.com/u-speak/core/tangle/store"
 
-	bolt "github.com/coreos/bbolt"
 	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
 )
 
 var (
-	dataBucketName = []byte("data")
-	tipBucketName  = []byte("tips")
+	dataBucketName       = []byte("data")
+	payloadBucketName    = []byte("payloads")
+	tipBucketName        = []byte("tips")
+	approverBucketName   = []byte("approvers")
+	quarantineBucketName = []byte("quarantine")
+	metaBucketName       = []byte("meta")
+	workBucketName       = []byte("work")
+	versionKey           = []byte("version")
 )
 
-// BoltStore stores its persistence data in a boltdb (github.com/coreos/bbolt)
+// BoltStore stores its persistence data in a boltdb (go.etcd.io/bbolt)
 type BoltStore struct {
 	db *bolt.DB
 }
@@ -25,39 +34,175 @@ func New(o store.Options) (*BoltStore, error) {
 	return s, s.Init(o)
 }
 
-// Add stores the data in the database
-func (b *BoltStore) Add(d *site.Site) error {
-	err := b.db.Update(func(tx *bolt.Tx) error {
-		bkt := tx.Bucket(dataBucketName)
-		return bkt.Put(d.Hash().Slice(), d.Serialize())
+// Add stores the site and its payload, indexes it as approver of its parents and updates the tips in one transaction
+func (b *BoltStore) Add(d *site.Site, payload []byte, add []hash.Hash, del []hash.Hash) error {
+	return b.AddMany([]store.Entry{{Site: d, Payload: payload}}, add, del)
+}
+
+// AddMany stores all entries and updates the tips in one transaction
+func (b *BoltStore) AddMany(es []store.Entry, add []hash.Hash, del []hash.Hash) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, e := range es {
+			err := putSite(tx, e.Site, e.Payload)
+			if err != nil {
+				return err
+			}
+		}
+		return setTips(tx, add, del)
 	})
+}
+
+func putSite(tx *bolt.Tx, d *site.Site, payload []byte) error {
+	h := d.Hash()
+	err := tx.Bucket(dataBucketName).Put(h.Slice(), d.Serialize())
 	if err != nil {
 		return err
 	}
-	return nil
+	if payload != nil {
+		err = tx.Bucket(payloadBucketName).Put(d.Content.Slice(), payload)
+		if err != nil {
+			return err
+		}
+	}
+	return indexApprover(tx, h, d.Validates)
 }
 
-// Get retrieves data from the database
-func (b *BoltStore) Get(h hash.Hash) *site.Site {
-	var d []byte
+// Payload returns the serialized payload with the content hash h
+func (b *BoltStore) Payload(h hash.Hash) ([]byte, error) {
+	var p []byte
 	err := b.db.View(func(tx *bolt.Tx) error {
-		bkt := tx.Bucket(dataBucketName)
-		d = bkt.Get(h.Slice())
+		v := tx.Bucket(payloadBucketName).Get(h.Slice())
+		if v == nil {
+			return store.ErrNotFound
+		}
+		p = append([]byte{}, v...)
 		return nil
 	})
-	if err != nil {
-		log.Error(err)
-	}
-	if d == nil {
+	return p, err
+}
+
+// PutPayloads stores the payloads in a single transaction
+func (b *BoltStore) PutPayloads(ps map[hash.Hash][]byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(payloadBucketName)
+		for h, p := range ps {
+			err := bkt.Put(h.Slice(), p)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Approvers returns the hashes of all sites directly validating h
+func (b *BoltStore) Approvers(h hash.Hash) []hash.Hash {
+	as := []hash.Hash{}
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(approverBucketName).Cursor()
+		prefix := h.Slice()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			as = append(as, hash.FromSlice(k[hash.HashSize:]))
+		}
 		return nil
+	})
+	return as
+}
+
+// indexApprover stores the relation between the approving site and its parents.
+// Keys consist of the parent hash followed by the approving hash.
+func indexApprover(tx *bolt.Tx, h hash.Hash, parents []hash.Hash) error {
+	bkt := tx.Bucket(approverBucketName)
+	for _, p := range parents {
+		err := bkt.Put(append(p.Slice(), h.Slice()...), []byte{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unindexApprover removes the relations added by indexApprover
+func unindexApprover(tx *bolt.Tx, h hash.Hash, parents []hash.Hash) error {
+	bkt := tx.Bucket(approverBucketName)
+	for _, p := range parents {
+		err := bkt.Delete(append(p.Slice(), h.Slice()...))
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// Has checks whether the site is stored
+func (b *BoltStore) Has(h hash.Hash) bool {
+	found := false
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(dataBucketName).Get(h.Slice()) != nil
+		return nil
+	})
+	return found
+}
+
+// Delete removes the site, its approver relations and its tip
+func (b *BoltStore) Delete(h hash.Hash) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(dataBucketName)
+		s := site.Site{}
+		if v := data.Get(h.Slice()); v != nil && s.Deserialize(v) == nil {
+			err := unindexApprover(tx, h, s.Validates)
+			if err != nil {
+				return err
+			}
+		}
+		err := tx.Bucket(tipBucketName).Delete(h.Slice())
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(workBucketName).Delete(h.Slice())
+		if err != nil {
+			return err
+		}
+		return data.Delete(h.Slice())
+	})
+}
+
+// ForEach walks the sites in the order of their hashes using a cursor of a single read transaction
+func (b *BoltStore) ForEach(fn func(hash.Hash, *site.Site) bool) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(dataBucketName).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var s *site.Site
+			d := site.Site{}
+			if d.Deserialize(v) == nil {
+				s = &d
+			}
+			if !fn(hash.FromSlice(k), s) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// Get retrieves the site from the database
+func (b *BoltStore) Get(h hash.Hash) (*site.Site, error) {
 	s := site.Site{}
-	err = s.Deserialize(d)
-	if err != nil {
-		log.Error(err)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		d := tx.Bucket(dataBucketName).Get(h.Slice())
+		if d == nil {
+			return store.ErrNotFound
+		}
+		if err := s.Deserialize(d); err != nil {
+			log.Errorf("Site %s: %s", h, err)
+			return store.ErrCorrupt
+		}
 		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return &s
+	return &s, nil
 }
 
 // Init the store
@@ -75,13 +220,98 @@ func (b *BoltStore) Init(o store.Options) error {
 		if err != nil {
 			return err
 		}
+		_, err = tx.CreateBucketIfNotExists(approverBucketName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(quarantineBucketName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(metaBucketName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(payloadBucketName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(workBucketName)
+		if err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return err
 	}
 	b.db = db
-	return nil
+	return b.migrate()
+}
+
+// Quarantine moves the raw site into the quarantine bucket, keeping it for inspection.
+// Unreadable sites are moved as well.
+func (b *BoltStore) Quarantine(h hash.Hash) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(dataBucketName)
+		v := data.Get(h.Slice())
+		if v == nil {
+			return tx.Bucket(tipBucketName).Delete(h.Slice())
+		}
+		err := tx.Bucket(quarantineBucketName).Put(h.Slice(), append([]byte{}, v...))
+		if err != nil {
+			return err
+		}
+		s := site.Site{}
+		if s.Deserialize(v) == nil {
+			err = unindexApprover(tx, h, s.Validates)
+			if err != nil {
+				return err
+			}
+		}
+		err = tx.Bucket(tipBucketName).Delete(h.Slice())
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(workBucketName).Delete(h.Slice())
+		if err != nil {
+			return err
+		}
+		return data.Delete(h.Slice())
+	})
+}
+
+// RecordWork stores the difficulties of stored sites as uvarints in the work bucket
+func (b *BoltStore) RecordWork(ws map[hash.Hash]int) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		data := tx.Bucket(dataBucketName)
+		bkt := tx.Bucket(workBucketName)
+		for h, w := range ws {
+			if data.Get(h.Slice()) == nil {
+				continue
+			}
+			v := make([]byte, binary.MaxVarintLen64)
+			err := bkt.Put(h.Slice(), v[:binary.PutUvarint(v, uint64(w))])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RecordedWork reads the work bucket, skipping unreadable entries
+func (b *BoltStore) RecordedWork() (map[hash.Hash]int, error) {
+	ws := make(map[hash.Hash]int)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(workBucketName).ForEach(func(k, v []byte) error {
+			if w, n := binary.Uvarint(v); n > 0 {
+				ws[hash.FromSlice(k)] = int(w)
+			}
+			return nil
+		})
+	})
+	return ws, err
 }
 
 // Close releases the lock on the db
@@ -93,24 +323,30 @@ func (b *BoltStore) Close() {
 }
 
 // SetTips applies the delata of tips
-func (b *BoltStore) SetTips(add hash.Hash, del []*site.Site) {
+func (b *BoltStore) SetTips(add []hash.Hash, del []hash.Hash) {
 	err := b.db.Update(func(tx *bolt.Tx) error {
-		bkt := tx.Bucket(tipBucketName)
-		for _, d := range del {
-			err := bkt.Delete(d.Hash().Slice())
-			if err != nil {
-				return err
-			}
+		return setTips(tx, add, del)
+	})
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func setTips(tx *bolt.Tx, add []hash.Hash, del []hash.Hash) error {
+	bkt := tx.Bucket(tipBucketName)
+	for _, d := range del {
+		err := bkt.Delete(d.Slice())
+		if err != nil {
+			return err
 		}
-		err := bkt.Put(add.Slice(), []byte{})
+	}
+	for _, a := range add {
+		err := bkt.Put(a.Slice(), []byte{})
 		if err != nil {
 			return err
 		}
-		return nil
-	})
-	if err != nil {
-		log.Error(err)
 	}
+	return nil
 }
 
 // GetTips returns the saved tips
@@ -4,60 +4,94 @@ import (
 	"github.com/u-speak/core/tangle/hash"
 	"github.com/u-speak/core/tangle/site"
 	"github.com/u-speak/core/tangle/store"
+	"github.com/u-speak/core/tangle/store/storetest"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack"
+	bolt "go.etcd.io/bbolt"
 )
 
-func TestInit(t *testing.T) {
-	s := BoltStore{}
-	err := s.Init(store.Options{Path: "/tmp/testInit.db"})
-	assert.NoError(t, err)
-	s.Close()
-	os.Remove("/tmp/testInit.db")
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		s, err := New(store.Options{Path: "/tmp/testStore.db"})
+		assert.NoError(t, err)
+		return s, func() { os.Remove("/tmp/testStore.db") }
+	})
 }
 
-func TestTips(t *testing.T) {
-	s := BoltStore{}
-	err := s.Init(store.Options{Path: "/tmp/testTips.db"})
-	assert.NoError(t, err)
-	defer os.Remove("/tmp/testTips.db")
-
-	assert.Empty(t, s.GetTips())
-	s1 := &site.Site{Content: hash.Hash{1}}
-	s2 := &site.Site{Content: hash.Hash{2}}
-	s3 := &site.Site{Content: hash.Hash{3}}
-	s.SetTips(s1.Hash(), nil)
-	assert.Equal(t, []hash.Hash{s1.Hash()}, s.GetTips())
-	s.SetTips(s2.Hash(), nil)
-	assert.Len(t, s.GetTips(), 2)
-	s.SetTips(s3.Hash(), []*site.Site{s2})
-	assert.Len(t, s.GetTips(), 2)
-	assert.NotContains(t, s.GetTips(), hash.Hash{2})
+func get(s *BoltStore, h hash.Hash) *site.Site {
+	st, _ := s.Get(h)
+	return st
 }
 
-func TestAddGet(t *testing.T) {
-	s := BoltStore{}
-	err := s.Init(store.Options{Path: "/tmp/testAddGet.db"})
-	assert.NoError(t, err)
-	defer s.Close()
-	defer os.Remove("/tmp/testAddGet.db")
+type legacySite struct {
+	Validates []*legacySite
+	Nonce     uint64
+	Content   hash.Hash
+	Type      string
+}
 
+func TestMigrate(t *testing.T) {
+	dbpath := "/tmp/testMigrate.db"
+	defer os.Remove(dbpath)
 	site1 := &site.Site{Content: hash.Hash{1, 3, 3, 7}}
-	site2 := &site.Site{Content: hash.Hash{1, 3, 3, 7}, Validates: []*site.Site{site1}}
-	site3 := &site.Site{Content: hash.Hash{1, 3, 3, 7}, Validates: []*site.Site{site1, site2}}
+	site2 := &site.Site{Content: hash.Hash{1, 3, 3, 8}, Validates: []hash.Hash{site1.Hash()}}
 
-	err = s.Add(site1)
+	db, err := bolt.Open(dbpath, 0644, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, site1, s.Get(site1.Hash()))
-
-	err = s.Add(site2)
+	err = db.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucket(dataBucketName)
+		if err != nil {
+			return err
+		}
+		l1 := &legacySite{Content: site1.Content}
+		l2 := &legacySite{Content: site2.Content, Validates: []*legacySite{l1}}
+		for _, e := range []struct {
+			h hash.Hash
+			l *legacySite
+		}{{site1.Hash(), l1}, {site2.Hash(), l2}} {
+			b, err := msgpack.Marshal(e.l)
+			if err != nil {
+				return err
+			}
+			err = bkt.Put(e.h.Slice(), b)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	assert.NoError(t, err)
-	assert.Equal(t, site2, s.Get(site2.Hash()))
+	assert.NoError(t, db.Close())
+
+	s := BoltStore{}
+	assert.NoError(t, s.Init(store.Options{Path: dbpath}))
+	defer s.Close()
+	assert.Equal(t, site2, get(&s, site2.Hash()))
+	assert.Equal(t, []hash.Hash{site2.Hash()}, s.Approvers(site1.Hash()))
+	_ = s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(dataBucketName).ForEach(func(_, v []byte) error {
+			assert.False(t, site.Legacy(v))
+			return nil
+		})
+	})
+}
 
-	err = s.Add(site3)
+func TestCorrupt(t *testing.T) {
+	s := BoltStore{}
+	err := s.Init(store.Options{Path: "/tmp/testCorrupt.db"})
 	assert.NoError(t, err)
-	assert.Equal(t, site3, s.Get(site3.Hash()))
-	assert.Equal(t, site2, s.Get(site3.Hash()).Validates[1])
+	defer s.Close()
+	defer os.Remove("/tmp/testCorrupt.db")
+
+	h := hash.Hash{1}
+	assert.NoError(t, s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(dataBucketName).Put(h.Slice(), []byte{site.FormatVersion, 0xc1})
+	}))
+	_, err = s.Get(h)
+	assert.Equal(t, store.ErrCorrupt, err)
+	_, err = s.Get(hash.Hash{2})
+	assert.Equal(t, store.ErrNotFound, err)
 }
@@ -1,45 +1,214 @@
 package memorystore
 
 import (
+	"sync"
+
 	"github.com/u-speak/core/tangle/hash"
 	"github.com/u-speak/core/tangle/site"
 	"github.com/u-speak/core/tangle/store"
 )
 
-// MemoryStore is a in-memory tangle store
+// MemoryStore is a in-memory tangle store. It is safe for concurrent use.
 type MemoryStore struct {
-	tips map[hash.Hash]bool
-	data map[hash.Hash]*site.Site
+	mu        sync.RWMutex
+	tips      map[hash.Hash]bool
+	data      map[hash.Hash]*site.Site
+	approvers map[hash.Hash][]hash.Hash
+	payloads  map[hash.Hash][]byte
+	// quarantine keeps sites removed by Quarantine
+	quarantine map[hash.Hash]*site.Site
+	// work keeps the difficulties passed to RecordWork
+	work map[hash.Hash]int
 }
 
 // Init initializes the maps
 func (m *MemoryStore) Init(store.Options) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.tips = make(map[hash.Hash]bool)
 	m.data = make(map[hash.Hash]*site.Site)
+	m.approvers = make(map[hash.Hash][]hash.Hash)
+	m.payloads = make(map[hash.Hash][]byte)
+	m.quarantine = make(map[hash.Hash]*site.Site)
+	m.work = make(map[hash.Hash]int)
+	return nil
+}
+
+// Add adds the record to the data section, stores the payload and applies the delta of tips
+func (m *MemoryStore) Add(s *site.Site, payload []byte, add []hash.Hash, del []hash.Hash) error {
+	return m.AddMany([]store.Entry{{Site: s, Payload: payload}}, add, del)
+}
+
+// AddMany adds all entries and applies the delta of tips
+func (m *MemoryStore) AddMany(es []store.Entry, add []hash.Hash, del []hash.Hash) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range es {
+		h := e.Site.Hash()
+		if _, ok := m.data[h]; !ok {
+			for _, p := range e.Site.Validates {
+				m.approvers[p] = append(m.approvers[p], h)
+			}
+		}
+		m.data[h] = e.Site
+		if e.Payload != nil {
+			m.payloads[e.Site.Content] = e.Payload
+		}
+	}
+	m.setTips(add, del)
+	return nil
+}
+
+// Payload returns the payload with the content hash h
+func (m *MemoryStore) Payload(h hash.Hash) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.payloads[h]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return p, nil
+}
+
+// PutPayloads stores the payloads
+func (m *MemoryStore) PutPayloads(ps map[hash.Hash][]byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for h, p := range ps {
+		m.payloads[h] = p
+	}
+	return nil
+}
+
+// Approvers returns the hashes of all sites directly validating h
+func (m *MemoryStore) Approvers(h hash.Hash) []hash.Hash {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]hash.Hash{}, m.approvers[h]...)
+}
+
+// Get returns the site
+func (m *MemoryStore) Get(h hash.Hash) (*site.Site, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.data[h]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return s, nil
+}
+
+// Has checks whether the site is stored
+func (m *MemoryStore) Has(h hash.Hash) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.data[h]
+	return ok
+}
+
+// Delete removes the site, its approver relations and its tip
+func (m *MemoryStore) Delete(h hash.Hash) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(h)
 	return nil
 }
 
-// Add adds the record to the data section
-func (m *MemoryStore) Add(s *site.Site) error {
-	m.data[s.Hash()] = s
+// ForEach calls fn for a snapshot of the stored sites in no particular order
+func (m *MemoryStore) ForEach(fn func(hash.Hash, *site.Site) bool) error {
+	m.mu.RLock()
+	hs := make([]hash.Hash, 0, len(m.data))
+	ss := make([]*site.Site, 0, len(m.data))
+	for h, s := range m.data {
+		hs = append(hs, h)
+		ss = append(ss, s)
+	}
+	m.mu.RUnlock()
+	for i, h := range hs {
+		if !fn(h, ss[i]) {
+			break
+		}
+	}
 	return nil
 }
 
-// Get returns the data
-func (m *MemoryStore) Get(h hash.Hash) *site.Site {
-	return m.data[h]
+// Quarantine moves the site out of the store
+func (m *MemoryStore) Quarantine(h hash.Hash) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.remove(h); s != nil {
+		m.quarantine[h] = s
+	}
+	return nil
+}
+
+// remove deletes the site and its relations, returning the removed site.
+// The caller must hold the write lock
+func (m *MemoryStore) remove(h hash.Hash) *site.Site {
+	delete(m.work, h)
+	s, ok := m.data[h]
+	if !ok {
+		delete(m.tips, h)
+		return nil
+	}
+	for _, p := range s.Validates {
+		as := m.approvers[p][:0]
+		for _, a := range m.approvers[p] {
+			if a != h {
+				as = append(as, a)
+			}
+		}
+		m.approvers[p] = as
+	}
+	delete(m.tips, h)
+	delete(m.data, h)
+	return s
+}
+
+// RecordWork keeps the difficulties reached by the proofs of work of stored sites
+func (m *MemoryStore) RecordWork(ws map[hash.Hash]int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for h, w := range ws {
+		if _, ok := m.data[h]; ok {
+			m.work[h] = w
+		}
+	}
+	return nil
+}
+
+// RecordedWork returns a copy of all recorded difficulties
+func (m *MemoryStore) RecordedWork() (map[hash.Hash]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ws := make(map[hash.Hash]int, len(m.work))
+	for h, w := range m.work {
+		ws[h] = w
+	}
+	return ws, nil
 }
 
 // SetTips applies the delta
-func (m *MemoryStore) SetTips(add hash.Hash, del []*site.Site) {
+func (m *MemoryStore) SetTips(add []hash.Hash, del []hash.Hash) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setTips(add, del)
+}
+
+// setTips expects the caller to hold the write lock
+func (m *MemoryStore) setTips(add []hash.Hash, del []hash.Hash) {
 	for _, d := range del {
-		delete(m.tips, d.Hash())
+		delete(m.tips, d)
+	}
+	for _, a := range add {
+		m.tips[a] = true
 	}
-	m.tips[add] = true
 }
 
 // GetTips returns the tips
 func (m *MemoryStore) GetTips() []hash.Hash {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	tips := []hash.Hash{}
 	for k := range m.tips {
 		tips = append(tips, k)
@@ -52,11 +221,15 @@ func (m *MemoryStore) Close() {}
 
 // Size returns the len of the data
 func (m *MemoryStore) Size() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return len(m.data)
 }
 
 // Hashes returns all stored hashes
 func (m *MemoryStore) Hashes() []hash.Hash {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	hs := []hash.Hash{}
 	for k := range m.data {
 		hs = append(hs, k)
@@ -1,22 +1,71 @@
 package store
 
 import (
+	"errors"
+
 	"github.com/u-speak/core/tangle/hash"
 	"github.com/u-speak/core/tangle/site"
 )
 
+var (
+	// ErrNotFound is returned when nothing is stored under the hash
+	ErrNotFound = errors.New("Not found")
+	// ErrCorrupt is returned when the stored data can not be decoded
+	ErrCorrupt = errors.New("Stored data is corrupt")
+)
+
 // Store is a persistant datastore
 type Store interface {
-	Add(*site.Site) error
-	Get(hash.Hash) *site.Site
+	// Add stores the site with its serialized payload and applies the delta of tips in a single transaction.
+	// Either all changes are persisted or none. A nil payload is not stored
+	Add(s *site.Site, payload []byte, add []hash.Hash, del []hash.Hash) error
+	// AddMany stores all entries and applies the delta of tips in a single transaction
+	AddMany(es []Entry, add []hash.Hash, del []hash.Hash) error
+	// Get returns the site, ErrNotFound if it is missing and ErrCorrupt if it can not be decoded
+	Get(hash.Hash) (*site.Site, error)
+	// Has checks whether a site is stored under the hash
+	Has(hash.Hash) bool
+	// Delete removes the site, its approver relations and its tip.
+	// The payload is kept, as other sites may reference the same content
+	Delete(hash.Hash) error
+	// ForEach calls fn for every stored site until fn returns false. Unreadable sites are passed as nil.
+	// fn must not call other methods of the store
+	ForEach(fn func(hash.Hash, *site.Site) bool) error
+	// Payload returns the serialized payload stored under the content hash, ErrNotFound if it is missing
+	Payload(hash.Hash) ([]byte, error)
+	// PutPayloads stores several payloads keyed by their content hash in a single transaction
+	PutPayloads(map[hash.Hash][]byte) error
+	// Approvers returns the hashes of all sites directly validating the site
+	Approvers(hash.Hash) []hash.Hash
 	Init(Options) error
-	SetTips(hash.Hash, []*site.Site)
+	// SetTips adds and removes tips in a single step
+	SetTips(add []hash.Hash, del []hash.Hash)
 	GetTips() []hash.Hash
 	Hashes() []hash.Hash
 	Size() int
 	Close()
 }
 
+// Entry is a site together with its serialized payload
+type Entry struct {
+	Site    *site.Site
+	Payload []byte
+}
+
+// Quarantiner is implemented by stores able to set broken sites aside.
+// Quarantined sites are no longer returned by the store and removed from the tips.
+type Quarantiner interface {
+	Quarantine(hash.Hash) error
+}
+
+// WorkRecorder is implemented by stores able to keep the difficulty reached by the proof of work of each site,
+// so memory hard proofs of work do not have to be recomputed whenever the tangle is loaded.
+// Recorded difficulties are dropped together with their site.
+type WorkRecorder interface {
+	RecordWork(map[hash.Hash]int) error
+	RecordedWork() (map[hash.Hash]int, error)
+}
+
 // Empty checks whether this store has been used before
 func Empty(s Store) bool {
 	return len(s.GetTips()) == 0
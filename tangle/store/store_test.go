@@ -0,0 +1,123 @@
+package store_test
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+	"github.com/u-speak/core/tangle/store"
+	"github.com/u-speak/core/tangle/store/badgerstore"
+	"github.com/u-speak/core/tangle/store/boltstore"
+	"github.com/u-speak/core/tangle/store/memorystore"
+)
+
+var engines = []struct {
+	name string
+	open func(path string) (store.Store, error)
+}{
+	{"memory", func(string) (store.Store, error) {
+		s := &memorystore.MemoryStore{}
+		return s, s.Init(store.Options{})
+	}},
+	{"bolt", func(path string) (store.Store, error) {
+		s := &boltstore.BoltStore{}
+		return s, s.Init(store.Options{Path: filepath.Join(path, "tangle.db")})
+	}},
+	{"badger", func(path string) (store.Store, error) {
+		return badgerstore.New(store.Options{Path: path})
+	}},
+}
+
+// sites returns a chain of n sites, each validating its predecessor
+func sites(n int) []*site.Site {
+	ss := make([]*site.Site, n)
+	var prev []hash.Hash
+	for i := range ss {
+		c := hash.Hash{}
+		binary.BigEndian.PutUint64(c[:], uint64(i))
+		ss[i] = &site.Site{Content: c, Validates: prev}
+		prev = []hash.Hash{ss[i].Hash()}
+	}
+	return ss
+}
+
+func bench(b *testing.B, fn func(b *testing.B, s store.Store)) {
+	for _, e := range engines {
+		b.Run(e.name, func(b *testing.B) {
+			dir, err := ioutil.TempDir("", "storebench")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			s, err := e.open(dir)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer s.Close()
+			fn(b, s)
+		})
+	}
+}
+
+// BenchmarkAdd adds sites one by one, each in its own synced transaction
+func BenchmarkAdd(b *testing.B) {
+	payload := make([]byte, 1024)
+	bench(b, func(b *testing.B, s store.Store) {
+		ss := sites(b.N)
+		b.ResetTimer()
+		for _, st := range ss {
+			err := s.Add(st, payload, []hash.Hash{st.Hash()}, st.Validates)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkAddParallel adds sites from concurrent writers, as during a splice of several peers
+func BenchmarkAddParallel(b *testing.B) {
+	payload := make([]byte, 1024)
+	bench(b, func(b *testing.B, s store.Store) {
+		ss := sites(b.N)
+		var i int64 = -1
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				st := ss[atomic.AddInt64(&i, 1)]
+				err := s.Add(st, payload, []hash.Hash{st.Hash()}, st.Validates)
+				if err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+}
+
+// BenchmarkAddMany adds sites in batches of 100
+func BenchmarkAddMany(b *testing.B) {
+	payload := make([]byte, 1024)
+	bench(b, func(b *testing.B, s store.Store) {
+		ss := sites(b.N)
+		b.ResetTimer()
+		for i := 0; i < len(ss); i += 100 {
+			j := i + 100
+			if j > len(ss) {
+				j = len(ss)
+			}
+			es := make([]store.Entry, 0, j-i)
+			for _, st := range ss[i:j] {
+				es = append(es, store.Entry{Site: st, Payload: payload})
+			}
+			err := s.AddMany(es, []hash.Hash{ss[j-1].Hash()}, ss[i].Validates)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
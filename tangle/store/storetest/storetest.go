@@ -0,0 +1,388 @@
+// Package storetest is a conformance suite for implementations of store.Store.
+// Backends prove their compatibility by running it from their own tests:
+//
+//	func TestStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+//			s := &MyStore{}
+//			assert.NoError(t, s.Init(store.Options{}))
+//			return s, nil
+//		})
+//	}
+package storetest
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+	"github.com/u-speak/core/tangle/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns a fresh, initialized and empty store. The suite closes the store
+// and calls cleanup afterwards, which may be nil
+type Factory func(t *testing.T) (s store.Store, cleanup func())
+
+// Volume is the number of sites added by the volume test, reduced in short mode
+var Volume = 10000
+
+// Run runs the conformance suite against stores created by f, each test on a fresh store
+func Run(t *testing.T, f Factory) {
+	for _, tc := range []struct {
+		name string
+		fn   func(*testing.T, store.Store)
+	}{
+		{"Empty", testEmpty},
+		{"Tips", testTips},
+		{"AddTips", testAddTips},
+		{"AddGet", testAddGet},
+		{"NotFound", testNotFound},
+		{"IdempotentAdd", testIdempotentAdd},
+		{"Approvers", testApprovers},
+		{"Payload", testPayload},
+		{"AddMany", testAddMany},
+		{"Iterate", testIterate},
+		{"Delete", testDelete},
+		{"Quarantine", testQuarantine},
+		{"RecordWork", testRecordWork},
+		{"Volume", testVolume},
+		{"Concurrency", testConcurrency},
+	} {
+		fn := tc.fn
+		t.Run(tc.name, func(t *testing.T) {
+			s, cleanup := f(t)
+			if cleanup != nil {
+				defer cleanup()
+			}
+			defer s.Close()
+			fn(t, s)
+		})
+	}
+}
+
+// chain returns n sites with distinct content, each validating its predecessor
+func chain(n int) []*site.Site {
+	ss := make([]*site.Site, n)
+	var prev []hash.Hash
+	for i := range ss {
+		c := hash.Hash{0xff}
+		binary.BigEndian.PutUint64(c[1:], uint64(i))
+		ss[i] = &site.Site{Content: c, Type: "test", Validates: prev}
+		prev = []hash.Hash{ss[i].Hash()}
+	}
+	return ss
+}
+
+func get(t *testing.T, s store.Store, h hash.Hash) *site.Site {
+	st, err := s.Get(h)
+	assert.NoError(t, err)
+	return st
+}
+
+func testEmpty(t *testing.T, s store.Store) {
+	assert.True(t, store.Empty(s))
+	assert.Empty(t, s.GetTips())
+	assert.Empty(t, s.Hashes())
+	assert.Equal(t, 0, s.Size())
+	assert.NoError(t, s.ForEach(func(hash.Hash, *site.Site) bool {
+		t.Error("ForEach called fn on an empty store")
+		return true
+	}))
+
+	st := &site.Site{Content: hash.Hash{1}}
+	assert.NoError(t, s.Add(st, nil, []hash.Hash{st.Hash()}, nil))
+	assert.False(t, store.Empty(s))
+}
+
+func testTips(t *testing.T, s store.Store) {
+	h1, h2, h3 := hash.Hash{1}, hash.Hash{2}, hash.Hash{3}
+	s.SetTips([]hash.Hash{h1}, nil)
+	assert.Equal(t, []hash.Hash{h1}, s.GetTips())
+	s.SetTips([]hash.Hash{h1}, nil)
+	assert.Equal(t, []hash.Hash{h1}, s.GetTips(), "tips are a set")
+	s.SetTips([]hash.Hash{h2}, nil)
+	assert.ElementsMatch(t, []hash.Hash{h1, h2}, s.GetTips())
+	s.SetTips([]hash.Hash{h3}, []hash.Hash{h2})
+	assert.ElementsMatch(t, []hash.Hash{h1, h3}, s.GetTips())
+	s.SetTips(nil, []hash.Hash{h2})
+	assert.ElementsMatch(t, []hash.Hash{h1, h3}, s.GetTips(), "removing a missing tip is a no-op")
+	s.SetTips([]hash.Hash{h2}, []hash.Hash{h2})
+	assert.ElementsMatch(t, []hash.Hash{h1, h2, h3}, s.GetTips(), "deletions are applied before additions")
+	s.SetTips(nil, []hash.Hash{h1, h2, h3})
+	assert.Empty(t, s.GetTips())
+	assert.True(t, store.Empty(s))
+}
+
+func testAddTips(t *testing.T, s store.Store) {
+	ss := chain(3)
+	assert.NoError(t, s.Add(ss[0], nil, []hash.Hash{ss[0].Hash()}, nil))
+	assert.Equal(t, []hash.Hash{ss[0].Hash()}, s.GetTips())
+	assert.NoError(t, s.Add(ss[1], nil, []hash.Hash{ss[1].Hash()}, ss[1].Validates))
+	assert.Equal(t, []hash.Hash{ss[1].Hash()}, s.GetTips())
+	assert.NoError(t, s.Add(ss[2], nil, nil, nil))
+	assert.Equal(t, []hash.Hash{ss[1].Hash()}, s.GetTips(), "tips are only changed by the delta")
+}
+
+func testAddGet(t *testing.T, s store.Store) {
+	site1 := &site.Site{Content: hash.Hash{1, 3, 3, 7}}
+	site2 := &site.Site{Content: hash.Hash{1, 3, 3, 7}, Validates: []hash.Hash{site1.Hash()}}
+	site3 := &site.Site{Content: hash.Hash{1, 3, 3, 7}, Validates: []hash.Hash{site1.Hash(), site2.Hash()}, Nonce: 42, Type: "post"}
+	for _, st := range []*site.Site{site1, site2, site3} {
+		assert.NoError(t, s.Add(st, nil, nil, nil))
+		assert.Equal(t, st, get(t, s, st.Hash()))
+		assert.True(t, s.Has(st.Hash()))
+	}
+	assert.Equal(t, site2.Hash(), get(t, s, site3.Hash()).Validates[1])
+	assert.Equal(t, 3, s.Size())
+	assert.ElementsMatch(t, []hash.Hash{site1.Hash(), site2.Hash(), site3.Hash()}, s.Hashes())
+}
+
+func testNotFound(t *testing.T, s store.Store) {
+	st, err := s.Get(hash.Hash{1})
+	assert.Equal(t, store.ErrNotFound, err)
+	assert.Nil(t, st)
+	_, err = s.Payload(hash.Hash{1})
+	assert.Equal(t, store.ErrNotFound, err)
+	assert.False(t, s.Has(hash.Hash{1}))
+	assert.Empty(t, s.Approvers(hash.Hash{1}))
+	assert.NoError(t, s.Delete(hash.Hash{1}))
+}
+
+func testIdempotentAdd(t *testing.T, s store.Store) {
+	ss := chain(2)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.Add(ss[0], []byte("zero"), []hash.Hash{ss[0].Hash()}, nil))
+		assert.NoError(t, s.Add(ss[1], []byte("one"), []hash.Hash{ss[1].Hash()}, ss[1].Validates))
+	}
+	assert.NoError(t, s.AddMany([]store.Entry{{Site: ss[0]}, {Site: ss[1]}}, nil, nil))
+	assert.Equal(t, 2, s.Size())
+	assert.Len(t, s.Hashes(), 2)
+	assert.Equal(t, []hash.Hash{ss[1].Hash()}, s.Approvers(ss[0].Hash()))
+	assert.Equal(t, []hash.Hash{ss[1].Hash()}, s.GetTips())
+	assert.Equal(t, ss[1], get(t, s, ss[1].Hash()))
+	p, err := s.Payload(ss[1].Content)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("one"), p, "adding without payload keeps the stored one")
+}
+
+func testApprovers(t *testing.T, s store.Store) {
+	site1 := &site.Site{Content: hash.Hash{1}}
+	site2 := &site.Site{Content: hash.Hash{2}, Validates: []hash.Hash{site1.Hash()}}
+	site3 := &site.Site{Content: hash.Hash{3}, Validates: []hash.Hash{site1.Hash(), site2.Hash()}}
+	for _, st := range []*site.Site{site1, site2, site3} {
+		assert.NoError(t, s.Add(st, nil, nil, nil))
+	}
+	assert.ElementsMatch(t, []hash.Hash{site2.Hash(), site3.Hash()}, s.Approvers(site1.Hash()))
+	assert.Equal(t, []hash.Hash{site3.Hash()}, s.Approvers(site2.Hash()))
+	assert.Empty(t, s.Approvers(site3.Hash()))
+}
+
+func testPayload(t *testing.T, s store.Store) {
+	site1 := &site.Site{Content: hash.Hash{1}}
+	site2 := &site.Site{Content: hash.Hash{2}, Validates: []hash.Hash{site1.Hash()}}
+	assert.NoError(t, s.Add(site1, []byte("one"), nil, nil))
+	assert.NoError(t, s.Add(site2, []byte("two"), nil, nil))
+	for h, want := range map[hash.Hash]string{site1.Content: "one", site2.Content: "two"} {
+		p, err := s.Payload(h)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(want), p)
+	}
+	_, err := s.Payload(site1.Hash())
+	assert.Equal(t, store.ErrNotFound, err, "payloads are keyed by the content hash")
+
+	ps := map[hash.Hash][]byte{}
+	for i := byte(0); i < 100; i++ {
+		ps[hash.Hash{3, i}] = []byte{i}
+	}
+	assert.NoError(t, s.PutPayloads(ps))
+	for h, want := range ps {
+		p, err := s.Payload(h)
+		assert.NoError(t, err)
+		assert.Equal(t, want, p)
+	}
+	assert.Equal(t, 2, s.Size(), "payloads are not sites")
+}
+
+func testAddMany(t *testing.T, s store.Store) {
+	ss := chain(3)
+	assert.NoError(t, s.AddMany(nil, []hash.Hash{{1}}, nil))
+	assert.Equal(t, []hash.Hash{{1}}, s.GetTips())
+	err := s.AddMany([]store.Entry{{Site: ss[0]}, {Site: ss[1], Payload: []byte("one")}, {Site: ss[2]}}, []hash.Hash{ss[2].Hash()}, []hash.Hash{{1}})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, s.Size())
+	assert.Equal(t, []hash.Hash{ss[2].Hash()}, s.GetTips())
+	assert.Equal(t, []hash.Hash{ss[2].Hash()}, s.Approvers(ss[1].Hash()))
+	p, err := s.Payload(ss[1].Content)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("one"), p)
+	_, err = s.Payload(ss[0].Content)
+	assert.Equal(t, store.ErrNotFound, err)
+}
+
+func testIterate(t *testing.T, s store.Store) {
+	ss := chain(20)
+	es := []store.Entry{}
+	want := map[hash.Hash]*site.Site{}
+	for _, st := range ss {
+		es = append(es, store.Entry{Site: st})
+		want[st.Hash()] = st
+	}
+	assert.NoError(t, s.AddMany(es, nil, nil))
+
+	seen := map[hash.Hash]*site.Site{}
+	assert.NoError(t, s.ForEach(func(h hash.Hash, st *site.Site) bool {
+		assert.NotContains(t, seen, h, "ForEach visits every site once")
+		seen[h] = st
+		return true
+	}))
+	assert.Equal(t, want, seen)
+
+	n := 0
+	assert.NoError(t, s.ForEach(func(hash.Hash, *site.Site) bool {
+		n++
+		return n < 5
+	}))
+	assert.Equal(t, 5, n, "ForEach stops when fn returns false")
+}
+
+func testDelete(t *testing.T, s store.Store) {
+	ss := chain(3)
+	err := s.AddMany([]store.Entry{{Site: ss[0]}, {Site: ss[1]}, {Site: ss[2], Payload: []byte("two")}}, []hash.Hash{ss[2].Hash()}, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Delete(ss[2].Hash()))
+	assert.False(t, s.Has(ss[2].Hash()))
+	_, err = s.Get(ss[2].Hash())
+	assert.Equal(t, store.ErrNotFound, err)
+	assert.Empty(t, s.GetTips())
+	assert.Empty(t, s.Approvers(ss[1].Hash()))
+	assert.Equal(t, []hash.Hash{ss[1].Hash()}, s.Approvers(ss[0].Hash()))
+	assert.Equal(t, 2, s.Size())
+	assert.ElementsMatch(t, []hash.Hash{ss[0].Hash(), ss[1].Hash()}, s.Hashes())
+	p, err := s.Payload(ss[2].Content)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("two"), p, "Delete keeps the payload")
+	assert.NoError(t, s.Delete(ss[2].Hash()))
+
+	assert.NoError(t, s.Add(ss[2], nil, nil, nil))
+	assert.Equal(t, []hash.Hash{ss[2].Hash()}, s.Approvers(ss[1].Hash()), "deleted sites can be added again")
+}
+
+func testQuarantine(t *testing.T, s store.Store) {
+	q, ok := s.(store.Quarantiner)
+	if !ok {
+		t.Skip("store does not implement store.Quarantiner")
+	}
+	ss := chain(2)
+	assert.NoError(t, s.Add(ss[0], nil, nil, nil))
+	assert.NoError(t, s.Add(ss[1], nil, []hash.Hash{ss[1].Hash()}, nil))
+
+	assert.NoError(t, q.Quarantine(ss[1].Hash()))
+	assert.NoError(t, q.Quarantine(ss[1].Hash()))
+	_, err := s.Get(ss[1].Hash())
+	assert.Equal(t, store.ErrNotFound, err)
+	assert.Equal(t, 1, s.Size())
+	assert.Empty(t, s.Approvers(ss[0].Hash()))
+	assert.Empty(t, s.GetTips())
+}
+
+func testRecordWork(t *testing.T, s store.Store) {
+	r, ok := s.(store.WorkRecorder)
+	if !ok {
+		t.Skip("store does not implement store.WorkRecorder")
+	}
+	ss := chain(3)
+	assert.NoError(t, s.AddMany([]store.Entry{{Site: ss[0]}, {Site: ss[1]}}, nil, nil))
+	ws, err := r.RecordedWork()
+	assert.NoError(t, err)
+	assert.Empty(t, ws)
+
+	err = r.RecordWork(map[hash.Hash]int{ss[0].Hash(): 3, ss[1].Hash(): 300, ss[2].Hash(): 5})
+	assert.NoError(t, err)
+	ws, err = r.RecordedWork()
+	assert.NoError(t, err)
+	assert.Equal(t, map[hash.Hash]int{ss[0].Hash(): 3, ss[1].Hash(): 300}, ws, "work is only recorded for stored sites")
+
+	assert.NoError(t, s.Delete(ss[1].Hash()))
+	ws, err = r.RecordedWork()
+	assert.NoError(t, err)
+	assert.Equal(t, map[hash.Hash]int{ss[0].Hash(): 3}, ws)
+	if q, ok := s.(store.Quarantiner); ok {
+		assert.NoError(t, q.Quarantine(ss[0].Hash()))
+		ws, err = r.RecordedWork()
+		assert.NoError(t, err)
+		assert.Empty(t, ws)
+	}
+}
+
+func testVolume(t *testing.T, s store.Store) {
+	n := Volume
+	if testing.Short() {
+		n /= 10
+	}
+	ss := chain(n)
+	for i := 0; i < n; i += 1000 {
+		j := i + 1000
+		if j > n {
+			j = n
+		}
+		es := make([]store.Entry, 0, j-i)
+		for _, st := range ss[i:j] {
+			es = append(es, store.Entry{Site: st, Payload: st.Content.Slice()})
+		}
+		require.NoError(t, s.AddMany(es, []hash.Hash{ss[j-1].Hash()}, ss[i].Validates))
+	}
+	assert.Equal(t, n, s.Size())
+	assert.Len(t, s.Hashes(), n)
+	assert.Equal(t, []hash.Hash{ss[n-1].Hash()}, s.GetTips())
+	count := 0
+	assert.NoError(t, s.ForEach(func(_ hash.Hash, st *site.Site) bool {
+		count++
+		return st != nil
+	}))
+	assert.Equal(t, n, count)
+	for i := 0; i < 100; i++ {
+		st := ss[rand.Intn(n)]
+		assert.Equal(t, st, get(t, s, st.Hash()))
+		p, err := s.Payload(st.Content)
+		assert.NoError(t, err)
+		assert.Equal(t, st.Content.Slice(), p)
+	}
+}
+
+func testConcurrency(t *testing.T, s store.Store) {
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w byte) {
+			defer wg.Done()
+			var prev []hash.Hash
+			for i := byte(0); i < 50; i++ {
+				st := &site.Site{Content: hash.Hash{w, i}}
+				assert.NoError(t, s.Add(st, []byte{w, i}, []hash.Hash{st.Hash()}, prev))
+				assert.Equal(t, st, get(t, s, st.Hash()))
+				s.GetTips()
+				s.Hashes()
+				s.Size()
+				prev = []hash.Hash{st.Hash()}
+			}
+		}(byte(w))
+	}
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				assert.NoError(t, s.ForEach(func(hash.Hash, *site.Site) bool { return true }))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 8*50, s.Size())
+	assert.Len(t, s.GetTips(), 8)
+}
@@ -2,12 +2,14 @@ package tangle
 
 import (
 	"math/rand"
-	"strings"
+	"runtime"
+	"sync"
+	"time"
 
-	"github.com/u-speak/core/img"
-	"github.com/u-speak/core/post"
 	"github.com/u-speak/core/tangle/datastore"
 	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/index"
+	"github.com/u-speak/core/tangle/pow"
 	"github.com/u-speak/core/tangle/site"
 	"github.com/u-speak/core/tangle/store"
 
@@ -15,25 +17,76 @@ import (
 )
 
 const (
-	// MinimumWeight for new site.Sites
-	MinimumWeight = 1
+	// MinimumDifficulty is the lowest number of leading zero bits accepted for new site.Sites.
+	// It equals the former minimum of one zero byte, so existing sites stay valid
+	MinimumDifficulty = 8
 	// MinimumValidations specifies how many sites must be verified by a new site
 	MinimumValidations = 2
 	// MaxRecommendations specifies how many sites can be returned by RecommendTips
 	MaxRecommendations = 4
+	// DefaultConfidenceSamples is the default number of tip selections used by Confidence
+	DefaultConfidenceSamples = 100
+	// DefaultConfirmationThreshold is the default confidence at which a site is confirmed
+	DefaultConfirmationThreshold = 0.95
 )
 
-// Tangle stores the relation between different transactions
+// Tangle stores the relation between different transactions.
+// It is safe for concurrent use.
 type Tangle struct {
-	tips  map[hash.Hash]bool
-	store store.Store
-	data  *datastore.Store
+	mu         sync.RWMutex
+	tips       map[hash.Hash]bool
+	store      store.Store
+	graph      *graph
+	index      index.Index
+	selector   TipSelector
+	samples    int
+	threshold  float64
+	network    string
+	genesis    []hash.Hash
+	difficulty int
+	pow        string
+
+	// tipGen is incremented whenever the tips change. It is guarded by mu
+	tipGen uint64
+	// sampleMu guards the tips selected for Confidence, which are reused until the tips change
+	sampleMu  sync.Mutex
+	sampled   []hash.Hash
+	sampleGen uint64
+
+	// subMu guards the subscriptions and the sites waiting for confirmation
+	subMu   sync.Mutex
+	subs    map[*Subscription]bool
+	pending []Event
+	confirm chan struct{}
+	done    chan struct{}
+	closed  sync.Once
 }
 
 // Options are used for initial configuration
 type Options struct {
-	Store    store.Store
+	// Store keeps the sites, their payloads and the tips
+	Store store.Store
+	// DataPath is the standalone payload database of older versions.
+	// If it exists, its payloads are moved into the Store on Init
 	DataPath string
+	// TipSelector is used by RecommendTips. Defaults to a RandomWalk with DefaultAlpha and DefaultDepth
+	TipSelector TipSelector
+	// ConfidenceSamples specifies how many tips are selected to compute the confidence of a site
+	ConfidenceSamples int
+	// ConfirmationThreshold is the confidence at which a site is considered confirmed
+	ConfirmationThreshold float64
+	// ConfirmationInterval specifies how often sites are checked for SiteConfirmed events. Defaults to DefaultConfirmationInterval
+	ConfirmationInterval time.Duration
+	// Index is the full text search index. Defaults to an index.MemoryIndex built from the store
+	Index index.Index
+	// Network identifies the network the tangle belongs to. Defaults to DefaultNetwork
+	Network string
+	// Genesis are the sites every tangle of the network starts with. Defaults to DefaultGenesis
+	Genesis []*site.Site
+	// Difficulty is the number of leading zero bits required for new sites. Defaults to MinimumDifficulty
+	Difficulty int
+	// PoW is the name of the proof of work algorithm new sites have to use. Defaults to pow.Default
+	PoW string
 }
 
 // Object is the exposed site including the content
@@ -44,50 +97,158 @@ type Object struct {
 
 // New returns a fresh initialized tangle
 func New(o Options) (*Tangle, error) {
-	ds, err := datastore.New(o.DataPath)
-	if err != nil {
-		return nil, err
-	}
-	t := &Tangle{data: ds}
+	t := &Tangle{}
 	return t, t.Init(o)
 }
 
 // Init initializes the tangle with two genesis blocks
 func (t *Tangle) Init(o Options) error {
-	t.tips = make(map[hash.Hash]bool)
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.store = o.Store
+	if o.DataPath != "" {
+		err := migrateData(t.store, o.DataPath)
+		if err != nil {
+			return err
+		}
+	}
+	t.selector = o.TipSelector
+	if t.selector == nil {
+		t.selector, _ = NewTipSelector("walk", DefaultAlpha, DefaultDepth)
+	}
+	t.samples = o.ConfidenceSamples
+	if t.samples <= 0 {
+		t.samples = DefaultConfidenceSamples
+	}
+	t.threshold = o.ConfirmationThreshold
+	if t.threshold <= 0 {
+		t.threshold = DefaultConfirmationThreshold
+	}
+	t.subs = make(map[*Subscription]bool)
+	t.confirm = make(chan struct{}, 1)
+	t.done = make(chan struct{})
+	t.difficulty = o.Difficulty
+	if t.difficulty <= 0 {
+		t.difficulty = MinimumDifficulty
+	}
+	t.pow = pow.Name(o.PoW)
+	if _, err := pow.Lookup(t.pow); err != nil {
+		return err
+	}
+	t.network = o.Network
+	if t.network == "" {
+		t.network = DefaultNetwork
+	}
+	gs := o.Genesis
+	if len(gs) == 0 {
+		gs = DefaultGenesis()
+	}
+	t.genesis = make([]hash.Hash, len(gs))
+	for i, g := range gs {
+		t.genesis[i] = g.Hash()
+	}
 	if store.Empty(t.store) {
-		gen1 := &site.Site{Content: hash.Hash{24, 67, 68, 72, 132, 181}, Nonce: 373, Type: "genesis"}
-		gen2 := &site.Site{Content: hash.Hash{24, 67, 68, 72, 132, 182}, Nonce: 510, Type: "genesis"}
-		err := t.store.Add(gen1)
+		es := make([]store.Entry, len(gs))
+		for i, g := range gs {
+			es[i] = store.Entry{Site: g}
+		}
+		err := t.store.AddMany(es, t.genesis, nil)
 		if err != nil {
 			return err
 		}
-		err = t.store.Add(gen2)
+	}
+	for _, g := range t.genesis {
+		if !t.store.Has(g) {
+			return ErrGenesisMismatch
+		}
+	}
+	t.load()
+	t.index = o.Index
+	if t.index == nil {
+		t.index = index.NewMemoryIndex()
+		err := t.rebuildIndex()
 		if err != nil {
 			return err
 		}
-		t.store.SetTips(gen1.Hash(), nil)
-		t.store.SetTips(gen2.Hash(), nil)
 	}
+	// Started last, so no goroutine is left behind when Init fails
+	interval := o.ConfirmationInterval
+	if interval <= 0 {
+		interval = DefaultConfirmationInterval
+	}
+	go t.watchConfirmations(interval)
+	return nil
+}
+
+// load reads the tips and the graph from the store. The caller must hold the write lock
+func (t *Tangle) load() {
+	t.tips = make(map[hash.Hash]bool)
 	for _, tip := range t.store.GetTips() {
 		t.tips[tip] = true
 	}
-	return nil
+	t.tipGen++
+	t.graph = newGraph()
+	recorded := map[hash.Hash]int{}
+	if r, ok := t.store.(store.WorkRecorder); ok {
+		var err error
+		if recorded, err = r.RecordedWork(); err != nil {
+			log.Error(err)
+			recorded = map[hash.Hash]int{}
+		}
+	}
+	// Memory hard proofs of work are slow to recompute, so missing weights are computed in parallel and recorded afterwards
+	g := t.graph
+	sites := make(chan *site.Site, runtime.NumCPU())
+	var mu sync.Mutex
+	computed := map[hash.Hash]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range sites {
+				h := s.Hash()
+				w := ownWeight(s)
+				mu.Lock()
+				computed[h] = w
+				mu.Unlock()
+				g.load(h, s.Validates, w, s.Time)
+			}
+		}()
+	}
+	err := t.store.ForEach(func(h hash.Hash, s *site.Site) bool {
+		if s == nil {
+			return true
+		}
+		if w, ok := recorded[h]; ok {
+			g.load(h, s.Validates, w, s.Time)
+		} else {
+			sites <- s
+		}
+		return true
+	})
+	close(sites)
+	wg.Wait()
+	if err != nil {
+		log.Error(err)
+	}
+	t.recordWork(computed)
 }
 
 // Add Validates the site and adds it to the tangle
 // to be valid, a site has to:
 // * Validate at least one tip
-// * Have a weight of at least MinimumWeight
+// * Have a proof of work with at least the RequiredDifficulty for its parents and payload size
 func (t *Tangle) Add(s *Object) error {
-	err := t.verifySite(s.Site)
+	work, err := t.verify(s, false)
 	if err != nil {
 		return err
 	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	v := func() bool {
 		for _, v := range s.Site.Validates {
-			if t.HasTip(v.Hash()) {
+			if t.tips[v] {
 				return true
 			}
 		}
@@ -96,7 +257,7 @@ func (t *Tangle) Add(s *Object) error {
 	if !v {
 		return ErrNotValidating
 	}
-	return t.addSite(s, true)
+	return t.addSite(s, true, work)
 }
 
 // Size returns the amount of sites in the tangle
@@ -107,229 +268,501 @@ func (t *Tangle) Size() int {
 // Tips returns a list of unconfirmed tips
 func (t *Tangle) Tips() []*site.Site {
 	keys := []*site.Site{}
-	for h := range t.tips {
-		s := t.Get(h)
-		if s != nil {
-			keys = append(keys, s.Site)
+	for _, h := range t.tipHashes() {
+		s, err := t.GetSite(h)
+		if err == nil {
+			keys = append(keys, s)
 		}
 	}
 	return keys
 }
 
-// Get retrieves the specified site
-func (t *Tangle) Get(h hash.Hash) *Object {
-	md := t.GetSite(h)
-	if md == nil {
-		return nil
+// Get retrieves the specified site including its data.
+// It returns ErrNotFound for unknown sites and ErrCorrupt if the site or its payload can not be read
+func (t *Tangle) Get(h hash.Hash) (*Object, error) {
+	md, err := t.GetSite(h)
+	if err != nil {
+		return nil, err
 	}
-	var data datastore.Serializable
-	switch md.Type {
-	case "post":
-		p := &post.Post{}
-		err := t.data.Get(p, md.Content)
-		if err != nil {
-			log.Error(err)
-			return nil
+	typ, err := datastore.Lookup(md.Type)
+	if err != nil {
+		return nil, err
+	}
+	data := typ.New()
+	if !typ.Virtual {
+		p, err := t.store.Payload(md.Content)
+		if err == store.ErrNotFound {
+			log.Errorf("Payload of site %s is missing", h)
+			return nil, ErrCorrupt
 		}
-		data = p
-	case "genesis":
-		data = &genesis{}
-	case "image":
-		i := &img.Image{}
-		err := t.data.Get(i, md.Content)
 		if err != nil {
-			log.Error(err)
-			return nil
+			return nil, err
 		}
-		data = i
-	case "dummy":
-		d := &dummydata{}
-		err := t.data.Get(d, md.Content)
+		err = data.Deserialize(p)
 		if err != nil {
-			log.Error(err)
-			return nil
+			log.Errorf("Payload of site %s: %s", h, err)
+			return nil, ErrCorrupt
 		}
-		data = d
-	default:
-		log.Errorf("Type `%s' not implemented", md.Type)
-		return nil
 	}
-	return &Object{Site: md, Data: data}
+	return &Object{Site: md, Data: data}, nil
+}
+
+// Difficulty returns the configured number of leading zero bits new sites need at least.
+// Use RequiredDifficulty for the difficulty of a specific site
+func (t *Tangle) Difficulty() int {
+	return t.difficulty
+}
+
+// PoW returns the name of the proof of work algorithm new sites have to use
+func (t *Tangle) PoW() string {
+	return t.pow
+}
+
+// Network returns the identifier of the network
+func (t *Tangle) Network() string {
+	return t.network
+}
+
+// Genesis returns the hashes of the genesis sites
+func (t *Tangle) Genesis() []hash.Hash {
+	return append([]hash.Hash{}, t.genesis...)
 }
 
-// GetSite returns the site without any data
-func (t *Tangle) GetSite(h hash.Hash) *site.Site {
+// GetSite returns the site without any data. It fails like Get
+func (t *Tangle) GetSite(h hash.Hash) (*site.Site, error) {
 	return t.store.Get(h)
 }
 
-// Close closes the underlying store
+// Close closes the underlying store and all subscriptions. Further calls do nothing
 func (t *Tangle) Close() {
-	t.store.Close()
-	t.data.Close()
+	t.closed.Do(func() {
+		close(t.done)
+		t.subMu.Lock()
+		for s := range t.subs {
+			delete(t.subs, s)
+			close(s.events)
+		}
+		t.subMu.Unlock()
+		t.store.Close()
+		t.index.Close()
+	})
 }
 
 // HasTip checks if the specified hash is a tip of the current tangle
 func (t *Tangle) HasTip(h hash.Hash) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	return t.tips[h]
 }
 
-// Weight returns the weight of a specific site inside the tangle
+func (t *Tangle) tipHashes() []hash.Hash {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	hs := make([]hash.Hash, 0, len(t.tips))
+	for h := range t.tips {
+		hs = append(hs, h)
+	}
+	return hs
+}
+
+// Weight returns the cumulative weight of a specific site inside the tangle.
+// It is the weight of the site itself plus the weight of all sites directly or indirectly validating it.
+// Sites outside of the tangle only weigh what their own proof of work reached
 func (t *Tangle) Weight(s *site.Site) int {
-	bound := make(map[*site.Site]bool)
-	// Setting up exclusion list
-	excl := make(map[hash.Hash]bool)
+	if w, ok := t.graph.weight(s.Hash()); ok {
+		return w
+	}
+	return ownWeight(s)
+}
 
-	inject := func(l []*site.Site) {
-		for _, v := range l {
-			bound[v] = true
-		}
+// ByteWeight returns the cumulative weight of a site counted in leading zero bytes instead of bits.
+// This is the unit older nodes used for Weight
+func (t *Tangle) ByteWeight(s *site.Site) int {
+	if w, ok := t.graph.byteWeight(s.Hash()); ok {
+		return w
 	}
+	return ownWeight(s) / 8
+}
 
-	inject(s.Validates)
-	for len(bound) != 0 {
-		for st := range bound {
-			delete(bound, st)
-			excl[st.Hash()] = true
-			for _, v := range st.Validates {
-				if !excl[v.Hash()] {
-					bound[v] = true
-				}
-			}
+// Parents returns the sites validated by s
+func (t *Tangle) Parents(s *site.Site) []*site.Site {
+	return s.Parents(t.store)
+}
+
+// Approvers returns the hashes of all sites directly validating h
+func (t *Tangle) Approvers(h hash.Hash) []hash.Hash {
+	return t.store.Approvers(h)
+}
+
+// Hashes returns all stored hashes. Prefer ForEach for large tangles
+func (t *Tangle) Hashes() []hash.Hash {
+	return t.store.Hashes()
+}
+
+// Has checks whether the site is part of the tangle
+func (t *Tangle) Has(h hash.Hash) bool {
+	return t.store.Has(h)
+}
+
+// ForEach calls fn for every stored site without its data until fn returns false. Unreadable sites are passed as nil.
+// fn must not call other methods of the tangle
+func (t *Tangle) ForEach(fn func(hash.Hash, *site.Site) bool) error {
+	return t.store.ForEach(fn)
+}
+
+// Sample returns up to n distinct hashes chosen uniformly at random, using reservoir sampling over the store
+func (t *Tangle) Sample(n int) ([]hash.Hash, error) {
+	hs := []hash.Hash{}
+	if n <= 0 {
+		return hs, nil
+	}
+	seen := 0
+	err := t.store.ForEach(func(h hash.Hash, _ *site.Site) bool {
+		seen++
+		if len(hs) < n {
+			hs = append(hs, h)
+		} else if j := rand.Intn(seen); j < n {
+			hs[j] = h
+		}
+		return true
+	})
+	rand.Shuffle(len(hs), func(i, j int) {
+		hs[i], hs[j] = hs[j], hs[i]
+	})
+	return hs, err
+}
+
+// RecommendTips returns up to MaxRecommendations distinct tips chosen by the tip selector.
+// If the selection yields less than MinimumValidations sites, it is filled up with sites validated by the tips.
+func (t *Tangle) RecommendTips() []*site.Site {
+	hs, err := t.SelectTips(MaxRecommendations)
+	if err != nil {
+		log.Error(err)
+	}
+	recs := []*site.Site{}
+	seen := make(map[hash.Hash]bool)
+	for _, h := range hs {
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		if s, err := t.GetSite(h); err == nil {
+			recs = append(recs, s)
 		}
 	}
-	// Calculating weight
-	rvl := make(map[hash.Hash][]*site.Site)
-	inject(t.Tips())
-	for len(bound) != 0 {
-		for st := range bound {
-			delete(bound, st)
-			for _, v := range st.Validates {
-				if !excl[v.Hash()] {
-					bound[v] = true
-					rvl[v.Hash()] = append(rvl[v.Hash()], st)
-				}
+	for i := 0; i < len(recs) && len(recs) < MinimumValidations; i++ {
+		for _, h := range recs[i].Validates {
+			if seen[h] || len(recs) >= MinimumValidations {
+				continue
+			}
+			seen[h] = true
+			if s, err := t.GetSite(h); err == nil {
+				recs = append(recs, s)
 			}
 		}
 	}
-	w := s.Hash().Weight()
-	inject(rvl[s.Hash()])
-	for len(bound) != 0 {
-		for st := range bound {
-			delete(bound, st)
-			excl[st.Hash()] = true
-			w += st.Hash().Weight()
-			for _, v := range rvl[st.Hash()] {
-				if !excl[v.Hash()] {
-					bound[v] = true
-				}
+	// Sites without parents, like the genesis, can only be completed with other tips
+	if len(recs) < MinimumValidations {
+		tips := t.tipHashes()
+		sortHashes(tips)
+		for _, h := range tips {
+			if seen[h] || len(recs) >= MinimumValidations {
+				continue
+			}
+			seen[h] = true
+			if s, err := t.GetSite(h); err == nil {
+				recs = append(recs, s)
 			}
 		}
 	}
-	return w
+	return recs
 }
 
-// Hashes returns all stored hashes
-func (t *Tangle) Hashes() []hash.Hash {
-	return t.store.Hashes()
+// SelectTips runs the tip selector n times. The result may contain duplicates
+func (t *Tangle) SelectTips(n int) ([]hash.Hash, error) {
+	hs := []hash.Hash{}
+	for i := 0; i < n; i++ {
+		h, err := t.selector.SelectTip(t)
+		if err != nil {
+			return hs, err
+		}
+		hs = append(hs, h)
+	}
+	return hs, nil
 }
 
-// RecommendTips returns tips to be used
-func (t *Tangle) RecommendTips() []*site.Site {
-	recs := t.Tips()
-	if len(recs) > MinimumValidations {
-		return recs[:MaxRecommendations]
-	}
-	blst := make(map[hash.Hash]bool)
-	hashes := t.Hashes()
-	for _, tip := range recs {
-		blst[tip.Hash()] = true
-	}
-	for len(recs) < MinimumValidations {
-		rndhash := hashes[rand.Int()%len(hashes)]
-		if blst[rndhash] {
-			continue
-		}
-		blst[rndhash] = true
-		s := t.Get(rndhash)
-		if s == nil {
-			continue
+// Confidence returns the share of selected tips that directly or indirectly validate the site.
+// The tip selector is run ConfidenceSamples times, the selection is shared by all sites until the tips change.
+func (t *Tangle) Confidence(h hash.Hash) float64 {
+	return t.confidence(h, t.sampleTips())
+}
+
+// sampleTips returns the tips selected for Confidence, selecting them again if the tips changed since the last call
+func (t *Tangle) sampleTips() []hash.Hash {
+	t.mu.RLock()
+	gen := t.tipGen
+	t.mu.RUnlock()
+	t.sampleMu.Lock()
+	defer t.sampleMu.Unlock()
+	if t.sampled != nil && t.sampleGen == gen {
+		return t.sampled
+	}
+	tips, err := t.SelectTips(t.samples)
+	if err != nil {
+		log.Error(err)
+		return tips
+	}
+	t.sampled, t.sampleGen = tips, gen
+	return tips
+}
+
+// confidence returns the share of tips directly or indirectly validating h
+func (t *Tangle) confidence(h hash.Hash, tips []hash.Hash) float64 {
+	if len(tips) == 0 {
+		return 0
+	}
+	approving := t.graph.approving(h)
+	n := 0
+	for _, tip := range tips {
+		if approving[tip] {
+			n++
 		}
-		recs = append(recs, s.Site)
 	}
-	return recs
+	return float64(n) / float64(len(tips))
+}
+
+// Confirmed checks whether the confidence of the site reaches the ConfirmationThreshold
+func (t *Tangle) Confirmed(h hash.Hash) bool {
+	return t.Confidence(h) >= t.threshold
+}
+
+// ConfirmationThreshold returns the confidence at which sites are considered confirmed
+func (t *Tangle) ConfirmationThreshold() float64 {
+	return t.threshold
 }
 
-// Inject adds sites to the tangle without checking for validated tips
+// Inject adds sites to the tangle without checking for validated tips.
+// Sites without time are rejected, history received through Splice or Import is added with Restore
 func (t *Tangle) Inject(s *Object, tip bool) error {
-	err := t.verifySite(s.Site)
+	return t.inject(s, tip, false)
+}
+
+// Restore adds a site of existing history like Inject. It also accepts sites without time,
+// which were created before sites carried a time and only need the configured difficulty
+func (t *Tangle) Restore(s *Object, tip bool) error {
+	return t.inject(s, tip, true)
+}
+
+func (t *Tangle) inject(s *Object, tip bool, history bool) error {
+	work, err := t.verify(s, history)
 	if err != nil {
 		return err
 	}
-	return t.addSite(s, tip)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.addSite(s, tip, work)
 }
 
-// Search performs a full text search for posts on the tangle
-func (t *Tangle) Search(s string) []*Object {
-	q := strings.ToLower(s)
-	type SR struct {
-		Match  bool
-		Object *Object
+// Search returns the posts containing all words and quoted phrases of the query
+func (t *Tangle) Search(q string) []*Object {
+	results := []*Object{}
+	for _, h := range t.index.Search(q) {
+		if o, err := t.Get(h); err == nil {
+			results = append(results, o)
+		}
 	}
-	hs := t.Hashes()
-	res := make(chan *SR, len(hs))
+	return results
+}
 
-	worker := func(h hash.Hash) {
-		o := t.Get(h)
-		if o == nil || o.Site.Type != "post" {
-			res <- &SR{Match: false}
-			return
+// RebuildIndex clears the search index and adds all stored sites again
+func (t *Tangle) RebuildIndex() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rebuildIndex()
+}
+
+// rebuildIndex expects the caller to hold the write lock
+func (t *Tangle) rebuildIndex() error {
+	err := t.index.Reset()
+	if err != nil {
+		return err
+	}
+	// Payloads can not be read while iterating, so only the hashes of indexable sites are collected
+	hs := []hash.Hash{}
+	err = t.store.ForEach(func(h hash.Hash, s *site.Site) bool {
+		if s == nil {
+			log.Errorf("Not indexing unreadable site %s", h)
+			return true
 		}
-		p := o.Data.(*post.Post)
-		if strings.Contains(strings.ToLower(p.Content), q) {
-			res <- &SR{Match: true, Object: o}
-		} else {
-			res <- &SR{Match: false}
+		typ, err := datastore.Lookup(s.Type)
+		if err != nil {
+			log.Errorf("Not indexing site %s: %s", h, err)
+			return true
+		}
+		if _, ok := typ.New().(datastore.Indexable); ok {
+			hs = append(hs, h)
 		}
+		return true
+	})
+	if err != nil {
+		return err
 	}
 	for _, h := range hs {
-		go worker(h)
-	}
-	results := []*Object{}
-	for i := 0; i < len(hs); i++ {
-		rs := <-res
-		if rs.Match {
-			results = append(results, rs.Object)
+		o, err := t.Get(h)
+		if err != nil {
+			log.Errorf("Not indexing site %s: %s", h, err)
+			continue
+		}
+		if i, ok := o.Data.(datastore.Indexable); ok {
+			err = t.index.Add(h, i.Text())
+			if err != nil {
+				return err
+			}
 		}
 	}
-	return results
+	return nil
+}
+
+// Verify checks whether the object could be added to the tangle, ignoring its parents.
+// Sites without time are only accepted as history
+func (t *Tangle) Verify(o *Object, history bool) error {
+	_, err := t.verify(o, history)
+	return err
 }
 
-func (t *Tangle) verifySite(s *site.Site) error {
-	if s.Hash().Weight() < MinimumWeight {
-		return ErrWeightTooLow
+// verify returns the difficulty reached by the proof of work of a valid object
+func (t *Tangle) verify(o *Object, history bool) (int, error) {
+	if o.Site.Time == 0 && !history {
+		return 0, ErrMissingTime
+	}
+	size, err := payloadSize(o)
+	if err != nil {
+		return 0, err
+	}
+	work, err := t.verifySite(o.Site, size)
+	if err != nil {
+		return 0, err
+	}
+	typ, err := datastore.Lookup(o.Site.Type)
+	if err != nil {
+		return 0, err
+	}
+	return work, typ.Check(o.Data)
+}
+
+// recordWork keeps the difficulties in stores implementing store.WorkRecorder.
+// Failing to record them only makes the next load slower, so errors are logged
+func (t *Tangle) recordWork(ws map[hash.Hash]int) {
+	r, ok := t.store.(store.WorkRecorder)
+	if !ok || len(ws) == 0 {
+		return
+	}
+	if err := r.RecordWork(ws); err != nil {
+		log.Error(err)
+	}
+}
+
+// ownWeight returns the weight a site adds to the cumulative weight of its ancestors,
+// which is the difficulty reached by its proof of work. Sites with an unknown algorithm weigh nothing
+func ownWeight(s *site.Site) int {
+	w, err := s.Work()
+	if err != nil {
+		return 0
+	}
+	return w.Difficulty()
+}
+
+// payloadSize returns the serialized size of the payload, which is 0 for virtual and unknown types
+func payloadSize(o *Object) (int, error) {
+	b, err := serializePayload(o)
+	return len(b), err
+}
+
+// serializePayload returns the stored form of the payload, nil for virtual and unknown types
+func serializePayload(o *Object) ([]byte, error) {
+	typ, err := datastore.Lookup(o.Site.Type)
+	if err != nil || typ.Virtual {
+		return nil, nil
+	}
+	if o.Data == nil {
+		return nil, ErrNoPayload
+	}
+	return o.Data.Serialize()
+}
+
+// verifySite checks the site itself and returns the difficulty reached by its proof of work
+func (t *Tangle) verifySite(s *site.Site, size int) (int, error) {
+	if pow.Name(s.PoW) != t.pow {
+		return 0, ErrWrongPoW
+	}
+	w, err := s.Work()
+	if err != nil {
+		return 0, err
+	}
+	required := t.difficulty
+	if s.Time != 0 {
+		required = t.RequiredDifficulty(s.Validates, size)
+	}
+	if w.Difficulty() < required {
+		return 0, ErrWeightTooLow
 	}
 	if len(s.Validates) < MinimumValidations {
-		return ErrTooFewValidations
+		return 0, ErrTooFewValidations
 	}
-	return nil
+	return w.Difficulty(), t.verifyTime(s)
 }
 
-func (t *Tangle) addSite(s *Object, tip bool) error {
-	for _, vs := range s.Site.Validates {
-		delete(t.tips, vs.Hash())
+// verifyTime checks that the site is not ahead of the local clock and not older than its known parents.
+// Once the tangle contains sites with time, all sites validating them need a time too
+func (t *Tangle) verifyTime(s *site.Site) error {
+	if s.Time < 0 || s.Time > time.Now().Unix()+MaxClockDrift {
+		return ErrTimeInFuture
 	}
-	if tip {
-		t.tips[s.Site.Hash()] = true
-		t.store.SetTips(s.Site.Hash(), s.Site.Validates)
+	for _, p := range s.Validates {
+		if pt, ok := t.graph.time(p); ok && s.Time < pt {
+			return ErrTimeBeforeParents
+		}
 	}
+	return nil
+}
 
-	err := t.store.Add(s.Site)
+// addSite expects the caller to hold the write lock. work is the difficulty reached by the proof of work, as returned by verify.
+// The site, its payload and the new tips are stored in a single transaction, the in-memory state is only updated afterwards.
+func (t *Tangle) addSite(s *Object, tip bool, work int) error {
+	payload, err := serializePayload(s)
 	if err != nil {
 		return err
 	}
-	err = t.data.Put(s.Data)
+	add := []hash.Hash{}
+	if tip {
+		add = append(add, s.Site.Hash())
+	}
+	err = t.store.Add(s.Site, payload, add, s.Site.Validates)
 	if err != nil {
 		return err
 	}
+
+	tipsChanged := tip
+	for _, vs := range s.Site.Validates {
+		if t.tips[vs] {
+			tipsChanged = true
+		}
+		delete(t.tips, vs)
+	}
+	if tip {
+		t.tips[s.Site.Hash()] = true
+	}
+	if tipsChanged {
+		t.tipGen++
+	}
+	t.recordWork(map[hash.Hash]int{s.Site.Hash(): work})
+	if i, ok := s.Data.(datastore.Indexable); ok {
+		err = t.index.Add(s.Site.Hash(), i.Text())
+		if err != nil {
+			return err
+		}
+	}
+	t.graph.add(s.Site.Hash(), s.Site.Validates, work, s.Site.Time)
+	t.publish(s, tipsChanged)
 	return nil
 }
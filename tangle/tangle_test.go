@@ -1,18 +1,36 @@
 package tangle
 
 import (
+	"bufio"
+	"fmt"
+	"math/rand"
 	"os"
+	"os/exec"
 	"path"
+	"runtime"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/datastore"
 	"github.com/u-speak/core/tangle/hash"
 	"github.com/u-speak/core/tangle/site"
 	"github.com/u-speak/core/tangle/store"
+	"github.com/u-speak/core/tangle/store/badgerstore"
 	"github.com/u-speak/core/tangle/store/boltstore"
 	"github.com/u-speak/core/tangle/store/memorystore"
 )
 
+// The dummy payload type is only registered for the tests, sites of the fixtures reference it by name
+func init() {
+	datastore.Register(datastore.Type{
+		Name: "dummy",
+		New:  func() datastore.Serializable { return &dummydata{} },
+	})
+}
+
 func dd(s string) *dummydata {
 	return &dummydata{content: s}
 }
@@ -37,50 +55,169 @@ func TestTips(t *testing.T) {
 	assert.Len(t, tngl.Tips(), 2)
 }
 
+func TestGenesis(t *testing.T) {
+	gs := NewGenesis("testnet")
+	assert.Equal(t, gs, NewGenesis("testnet"))
+	assert.NotEqual(t, gs, NewGenesis("staging"))
+	assert.Equal(t, DefaultGenesis(), NewGenesis(""))
+	for _, g := range gs {
+		assert.True(t, g.Hash().Difficulty() >= MinimumDifficulty)
+	}
+
+	st := ms()
+	tngl := Tangle{}
+	err := tngl.Init(Options{Store: st, Network: "testnet", Genesis: gs})
+	assert.NoError(t, err)
+	assert.Equal(t, "testnet", tngl.Network())
+	assert.ElementsMatch(t, []hash.Hash{gs[0].Hash(), gs[1].Hash()}, tngl.Genesis())
+	assert.ElementsMatch(t, tngl.Genesis(), tngl.tipHashes())
+
+	other := Tangle{}
+	assert.Equal(t, ErrGenesisMismatch, other.Init(Options{Store: st}))
+	assert.Equal(t, DefaultNetwork, other.Network())
+}
+
 func TestGet(t *testing.T) {
-	tngl, err := New(Options{Store: ms(), DataPath: path.Join(os.TempDir(), "testget")})
+	tngl, err := New(Options{Store: ms()})
 	assert.NoError(t, err)
-	assert.Nil(t, tngl.Get(hash.Hash{}))
+	_, err = tngl.Get(hash.Hash{})
+	assert.Equal(t, ErrNotFound, err)
 	for _, s := range tngl.Tips() {
-		assert.Equal(t, s, tngl.Get(s.Hash()).Site)
+		o, err := tngl.Get(s.Hash())
+		assert.NoError(t, err)
+		assert.Equal(t, s, o.Site)
 	}
 }
 
+func TestGetCorrupt(t *testing.T) {
+	st := ms()
+	tngl, err := New(Options{Store: st})
+	assert.NoError(t, err)
+	gen := tngl.tipHashes()
+	h, _ := dd("corrupt").Hash()
+	s := &site.Site{Content: h, Type: "dummy", Validates: gen}
+	s.Mine(MinimumDifficulty)
+	assert.NoError(t, st.Add(s, nil, nil, nil))
+	_, err = tngl.Get(s.Hash())
+	assert.Equal(t, ErrCorrupt, err)
+	u := &site.Site{Content: h, Type: "unknown", Validates: gen}
+	assert.NoError(t, st.Add(u, nil, nil, nil))
+	_, err = tngl.Get(u.Hash())
+	assert.Equal(t, datastore.ErrUnknownType, err)
+}
+
+// mine stamps the site with the current time and mines it to the difficulty the tangle requires
+func mine(tngl *Tangle, s *site.Site) {
+	s.Time = time.Now().Unix()
+	s.Mine(tngl.RequiredDifficulty(s.Validates, 0))
+}
+
+// siteOf returns the site or nil if it can not be read
+func siteOf(tngl *Tangle, h hash.Hash) *site.Site {
+	s, _ := tngl.GetSite(h)
+	return s
+}
+
 func TestAdd(t *testing.T) {
-	tngl, err := New(Options{Store: ms(), DataPath: path.Join(os.TempDir(), "testadd")})
+	tngl, err := New(Options{Store: ms()})
 	assert.NoError(t, err)
 	tips := tngl.Tips()
-	err = tngl.Add(&Object{Site: &site.Site{Content: hash.Hash{1, 3, 3, 7}, Nonce: 0}, Data: dd("1337")})
+	err = tngl.Add(&Object{Site: &site.Site{Content: hash.Hash{1, 3, 3, 7}, Nonce: 0, Time: 1}, Data: dd("1337")})
 	assert.Equal(t, ErrWeightTooLow, err)
 	st := &Object{Site: &site.Site{Content: hash.Hash{1, 3, 3, 7}}, Data: dd("1337")}
-	st.Site.Mine(1)
+	mine(tngl, st.Site)
 	err = tngl.Add(st)
 	assert.Equal(t, ErrTooFewValidations, err)
 
 	h, _ := dd("1337").Hash()
-	sub := &Object{Site: &site.Site{Content: h, Nonce: 0, Validates: []*site.Site{tips[0], tips[1]}, Type: "dummy"}, Data: dd("1337")}
-	sub.Site.Mine(1)
+	sub := &Object{Site: &site.Site{Content: h, Nonce: 0, Validates: []hash.Hash{tips[0].Hash(), tips[1].Hash()}, Type: "dummy"}, Data: dd("1337")}
+	mine(tngl, sub.Site)
 	err = tngl.Add(sub)
 	assert.NoError(t, err)
 	assert.False(t, tngl.tips[tips[0].Hash()])
 	assert.False(t, tngl.tips[tips[1].Hash()])
 	assert.True(t, tngl.tips[sub.Site.Hash()])
-	assert.Equal(t, sub, tngl.Get(sub.Site.Hash()))
+	o, err := tngl.Get(sub.Site.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, sub, o)
+}
+
+func TestDifficulty(t *testing.T) {
+	tngl, err := New(Options{Store: ms(), Difficulty: 12})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	assert.Equal(t, 12, tngl.Difficulty())
+	tips := tngl.Tips()
+	h, _ := dd("difficulty").Hash()
+	o := &Object{Site: &site.Site{Content: h, Validates: []hash.Hash{tips[0].Hash(), tips[1].Hash()}, Type: "dummy", Time: time.Now().Unix()}, Data: dd("difficulty")}
+	for o.Site.Mine(MinimumDifficulty); o.Site.Hash().Difficulty() >= 12; o.Site.Mine(MinimumDifficulty) {
+		o.Site.Nonce++
+	}
+	assert.Equal(t, ErrWeightTooLow, tngl.Add(o))
+	o.Site.Mine(12)
+	assert.NoError(t, tngl.Add(o))
+}
+
+func TestPoW(t *testing.T) {
+	_, err := New(Options{Store: ms(), PoW: "sha256"})
+	assert.Error(t, err)
+	st := ms()
+	tngl, err := New(Options{Store: st, Difficulty: 2, PoW: "argon2id"})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	assert.Equal(t, "argon2id", tngl.PoW())
+	tips := tngl.Tips()
+	h, _ := dd("pow").Hash()
+	o := &Object{Site: &site.Site{Content: h, Validates: []hash.Hash{tips[0].Hash(), tips[1].Hash()}, Type: "dummy", Time: time.Now().Unix()}, Data: dd("pow")}
+	o.Site.Mine(2)
+	assert.Equal(t, ErrWrongPoW, tngl.Add(o))
+	o.Site.PoW = "argon2id"
+	// mined above the required difficulty, so the weight has to come from the proof of work
+	o.Site.Mine(4)
+	w, err := o.Site.Work()
+	assert.NoError(t, err)
+	assert.Equal(t, w.Difficulty(), tngl.Weight(o.Site), "sites outside of the tangle weigh their proof of work")
+	assert.NoError(t, tngl.Add(o))
+	assert.Equal(t, w.Difficulty(), tngl.Weight(o.Site))
+	ws, err := st.RecordedWork()
+	assert.NoError(t, err)
+	assert.Equal(t, w.Difficulty(), ws[o.Site.Hash()])
+	assert.Len(t, ws, 3, "the weights of the genesis sites are recorded while loading")
+
+	// load reads the recorded work instead of recomputing it
+	assert.NoError(t, st.RecordWork(map[hash.Hash]int{o.Site.Hash(): 100}))
+	tngl.mu.Lock()
+	tngl.load()
+	tngl.mu.Unlock()
+	assert.Equal(t, 100, tngl.Weight(o.Site))
+}
+
+func TestInitFailure(t *testing.T) {
+	st := ms()
+	tngl, err := New(Options{Store: st, Network: "testnet", Genesis: NewGenesis("testnet")})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		_, err = New(Options{Store: ms(), PoW: "sha256"})
+		assert.Error(t, err)
+		_, err = New(Options{Store: st})
+		assert.Equal(t, ErrGenesisMismatch, err)
+	}
+	assert.True(t, runtime.NumGoroutine() <= before, "failed Init left goroutines behind")
 }
 
 func TestRestore(t *testing.T) {
 	dbpath := path.Join(os.TempDir(), "testRestore.db")
 	defer os.Remove(dbpath)
-	datapath := path.Join(os.TempDir(), "testRestoreData.db")
-	defer os.Remove(datapath)
 	bs := boltstore.BoltStore{}
 	err := bs.Init(store.Options{Path: dbpath})
 	assert.NoError(t, err)
-	tngl, err := New(Options{Store: &bs, DataPath: datapath})
+	tngl, err := New(Options{Store: &bs})
 	assert.NoError(t, err)
 	tips := tngl.Tips()
-	sub := &Object{Site: &site.Site{Content: hash.Hash{1, 3, 3, 7}, Nonce: 0, Validates: []*site.Site{tips[0], tips[1]}, Type: "dummy"}, Data: dd("1337")}
-	sub.Site.Mine(1)
+	sub := &Object{Site: &site.Site{Content: hash.Hash{1, 3, 3, 7}, Nonce: 0, Validates: []hash.Hash{tips[0].Hash(), tips[1].Hash()}, Type: "dummy"}, Data: dd("1337")}
+	mine(tngl, sub.Site)
 	err = tngl.Add(sub)
 	assert.NoError(t, err)
 	tips = tngl.Tips()
@@ -89,15 +226,13 @@ func TestRestore(t *testing.T) {
 	bs2 := boltstore.BoltStore{}
 	err = bs2.Init(store.Options{Path: dbpath})
 	assert.NoError(t, err)
-	tngl2, err := New(Options{Store: &bs2, DataPath: datapath})
+	tngl2, err := New(Options{Store: &bs2})
 	assert.NoError(t, err)
 	assert.Equal(t, tips, tngl2.Tips())
 }
 
 func TestWeight(t *testing.T) {
-	dbpath := path.Join(os.TempDir(), "testweight.db")
-	defer os.Remove(dbpath)
-	tngl, err := New(Options{Store: ms(), DataPath: dbpath})
+	tngl, err := New(Options{Store: ms()})
 	assert.NoError(t, err)
 	tips := tngl.Tips()
 	gen1, gen2 := tips[0], tips[1]
@@ -109,46 +244,287 @@ func TestWeight(t *testing.T) {
 	s2dh, _ := s2d.Hash()
 	s3dh, _ := s3d.Hash()
 	s4dh, _ := s4d.Hash()
-	s1 := &Object{Site: &site.Site{Content: s1dh, Nonce: 0, Type: "dummy", Validates: []*site.Site{gen1, gen2}}, Data: s1d}
-	s2 := &Object{Site: &site.Site{Content: s2dh, Nonce: 0, Type: "dummy", Validates: []*site.Site{s1.Site, gen2}}, Data: s2d}
-	s3 := &Object{Site: &site.Site{Content: s3dh, Nonce: 0, Type: "dummy", Validates: []*site.Site{s2.Site, s1.Site}}, Data: s3d}
-	s4 := &Object{Site: &site.Site{Content: s4dh, Nonce: 0, Type: "dummy", Validates: []*site.Site{s3.Site, s2.Site}}, Data: s4d}
-	s1.Site.Mine(1)
-	s2.Site.Mine(1)
-	s3.Site.Mine(1)
-	s4.Site.Mine(1)
+	s1 := &Object{Site: &site.Site{Content: s1dh, Nonce: 0, Type: "dummy", Validates: []hash.Hash{gen1.Hash(), gen2.Hash()}}, Data: s1d}
+	mine(tngl, s1.Site)
+	s2 := &Object{Site: &site.Site{Content: s2dh, Nonce: 0, Type: "dummy", Validates: []hash.Hash{s1.Site.Hash(), gen2.Hash()}}, Data: s2d}
+	mine(tngl, s2.Site)
+	s3 := &Object{Site: &site.Site{Content: s3dh, Nonce: 0, Type: "dummy", Validates: []hash.Hash{s2.Site.Hash(), s1.Site.Hash()}}, Data: s3d}
+	mine(tngl, s3.Site)
+	s4 := &Object{Site: &site.Site{Content: s4dh, Nonce: 0, Type: "dummy", Validates: []hash.Hash{s3.Site.Hash(), s2.Site.Hash()}}, Data: s4d}
+	mine(tngl, s4.Site)
 	assert.NoError(t, tngl.Add(s1))
 	assert.NoError(t, tngl.Add(s2))
 	assert.NoError(t, tngl.Add(s3))
 	assert.NoError(t, tngl.Add(s4))
 	assert.EqualValues(t, 6, tngl.Size())
 	tngl.Weight(s2.Site)
-	assert.EqualValues(t, s4.Site.Hash().Weight(), tngl.Weight(s4.Site))
-	assert.EqualValues(t, s4.Site.Hash().Weight()+s3.Site.Hash().Weight(), tngl.Weight(s3.Site))
-	assert.EqualValues(t, s4.Site.Hash().Weight()+s3.Site.Hash().Weight()+s2.Site.Hash().Weight(), tngl.Weight(s2.Site))
-	assert.EqualValues(t, s4.Site.Hash().Weight()+s3.Site.Hash().Weight()+s2.Site.Hash().Weight()+s1.Site.Hash().Weight(), tngl.Weight(s1.Site))
+	assert.EqualValues(t, s4.Site.Hash().Difficulty(), tngl.Weight(s4.Site))
+	assert.EqualValues(t, s4.Site.Hash().Difficulty()+s3.Site.Hash().Difficulty(), tngl.Weight(s3.Site))
+	assert.EqualValues(t, s4.Site.Hash().Difficulty()+s3.Site.Hash().Difficulty()+s2.Site.Hash().Difficulty(), tngl.Weight(s2.Site))
+	assert.EqualValues(t, s4.Site.Hash().Difficulty()+s3.Site.Hash().Difficulty()+s2.Site.Hash().Difficulty()+s1.Site.Hash().Difficulty(), tngl.Weight(s1.Site))
+	// the byte weight sums up whole leading zero bytes like older nodes did
+	assert.EqualValues(t, s4.Site.Hash().Weight()+s3.Site.Hash().Weight(), tngl.ByteWeight(s3.Site))
+	assert.EqualValues(t, s4.Site.Hash().Weight()+s3.Site.Hash().Weight()+s2.Site.Hash().Weight()+s1.Site.Hash().Weight(), tngl.ByteWeight(s1.Site))
+	unknown := &site.Site{Content: s4dh, Type: "dummy", Validates: []hash.Hash{s4.Site.Hash()}, Time: s4.Site.Time}
+	unknown.Mine(16)
+	assert.Equal(t, unknown.Hash().Weight(), tngl.ByteWeight(unknown))
+	assert.Equal(t, unknown.Hash().Difficulty(), tngl.Weight(unknown))
+}
+
+func TestConcurrentAdd(t *testing.T) {
+	tngl, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	gen := tngl.Tips()
+
+	mk := func(content string, tip *site.Site) *Object {
+		d := dd(content)
+		h, _ := d.Hash()
+		other := gen[0]
+		if tip.Hash() == other.Hash() {
+			other = gen[1]
+		}
+		s := &site.Site{Content: h, Type: "dummy", Validates: []hash.Hash{tip.Hash(), other.Hash()}}
+		mine(tngl, s)
+		return &Object{Site: s, Data: d}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	added := 0
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				tips := tngl.Tips()
+				o := mk("add"+strconv.Itoa(w)+"-"+strconv.Itoa(i), tips[i%len(tips)])
+				var err error
+				if i%3 == 0 {
+					err = tngl.Inject(o, true)
+				} else {
+					err = tngl.Add(o)
+				}
+				if err == ErrNotValidating {
+					continue
+				}
+				assert.NoError(t, err)
+				mu.Lock()
+				added++
+				mu.Unlock()
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				for _, tip := range tngl.Tips() {
+					tngl.HasTip(tip.Hash())
+					tngl.Weight(tip)
+				}
+				tngl.Size()
+				tngl.Search("add")
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2+added, tngl.Size())
+	validated := make(map[hash.Hash]bool)
+	for _, h := range tngl.Hashes() {
+		for _, v := range siteOf(tngl, h).Validates {
+			validated[v] = true
+		}
+	}
+	for _, h := range tngl.Hashes() {
+		assert.Equal(t, !validated[h], tngl.HasTip(h))
+	}
+}
+
+// bruteWeight computes the cumulative weight by searching all sites validating s
+func bruteWeight(tngl *Tangle, s hash.Hash) int {
+	approvers := make(map[hash.Hash][]hash.Hash)
+	for _, h := range tngl.Hashes() {
+		for _, v := range siteOf(tngl, h).Validates {
+			approvers[v] = append(approvers[v], h)
+		}
+	}
+	seen := map[hash.Hash]bool{s: true}
+	bound := []hash.Hash{s}
+	w := 0
+	for len(bound) > 0 {
+		h := bound[0]
+		bound = bound[1:]
+		w += h.Difficulty()
+		for _, a := range approvers[h] {
+			if !seen[a] {
+				seen[a] = true
+				bound = append(bound, a)
+			}
+		}
+	}
+	return w
+}
+
+// randomTangle injects n sites validating random existing sites
+func randomTangle(tb testing.TB, tngl *Tangle, n int, seed int64) []hash.Hash {
+	rnd := rand.New(rand.NewSource(seed))
+	hs := tngl.Hashes()
+	for i := 0; i < n; i++ {
+		d := dd("random" + strconv.Itoa(i))
+		ch, _ := d.Hash()
+		recent := 8
+		if len(hs) < recent {
+			recent = len(hs)
+		}
+		p1 := hs[len(hs)-1-rnd.Intn(recent)]
+		p2 := hs[rnd.Intn(len(hs))]
+		for p2 == p1 {
+			p2 = hs[rnd.Intn(len(hs))]
+		}
+		s := &site.Site{Content: ch, Type: "dummy", Validates: []hash.Hash{p1, p2}}
+		mine(tngl, s)
+		assert.NoError(tb, tngl.Inject(&Object{Site: s, Data: d}, true))
+		hs = append(hs, s.Hash())
+	}
+	return hs
+}
+
+func TestWeightIncremental(t *testing.T) {
+	st := ms()
+	tngl, err := New(Options{Store: st})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	hs := randomTangle(t, tngl, 40, 1)
+	// Warm up parts of the cache before adding more sites
+	for _, h := range hs[:20] {
+		tngl.Weight(siteOf(tngl, h))
+	}
+	hs = append(hs, randomTangle(t, tngl, 40, 2)...)
+	for _, h := range hs {
+		assert.Equal(t, bruteWeight(tngl, h), tngl.Weight(siteOf(tngl, h)))
+	}
+
+	restored := &Tangle{}
+	assert.NoError(t, restored.Init(Options{Store: st}))
+	for _, h := range hs {
+		assert.Equal(t, bruteWeight(restored, h), restored.Weight(siteOf(restored, h)))
+	}
 }
 
 func BenchmarkWeight(b *testing.B) {
-	tngl := Tangle{}
-	err := tngl.Init(Options{Store: ms()})
+	tngl, err := New(Options{Store: ms()})
 	assert.NoError(b, err)
-	tips := tngl.Tips()
-	gen1, gen2 := tips[0], tips[1]
-	s1 := &site.Site{Content: hash.Hash{72, 132, 196, 211, 77, 53}, Nonce: 0, Validates: []*site.Site{gen1, gen2}}
-	s2 := &site.Site{Content: hash.Hash{72, 132, 196, 211, 77, 54}, Nonce: 0, Validates: []*site.Site{s1, gen2}}
-	s3 := &site.Site{Content: hash.Hash{72, 132, 196, 211, 77, 55}, Nonce: 0, Validates: []*site.Site{s2, s1}}
-	s4 := &site.Site{Content: hash.Hash{72, 132, 196, 211, 77, 56}, Nonce: 0, Validates: []*site.Site{s3, s2}}
-	s1.Mine(1)
-	s2.Mine(1)
-	s3.Mine(1)
-	s4.Mine(1)
-	assert.NoError(b, tngl.Add(&Object{Site: s1, Data: dd("s1")}))
-	assert.NoError(b, tngl.Add(&Object{Site: s2, Data: dd("s2")}))
-	assert.NoError(b, tngl.Add(&Object{Site: s3, Data: dd("s3")}))
-	assert.NoError(b, tngl.Add(&Object{Site: s4, Data: dd("s4")}))
+	defer tngl.Close()
+	hs := randomTangle(b, tngl, 500, 1)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		tngl.Weight(s1)
+		tngl.Weight(siteOf(tngl, hs[i%len(hs)]))
+	}
+}
+
+// crashEnv names the database the child process of TestCrashRecovery writes to,
+// crashEngineEnv the store engine it uses
+const (
+	crashEnv       = "TANGLE_CRASH_DB"
+	crashEngineEnv = "TANGLE_CRASH_ENGINE"
+)
+
+var crashEngines = map[string]func(path string) (store.Store, error){
+	"bolt": func(path string) (store.Store, error) {
+		bs := &boltstore.BoltStore{}
+		return bs, bs.Init(store.Options{Path: path})
+	},
+	"badger": func(path string) (store.Store, error) {
+		return badgerstore.New(store.Options{Path: path})
+	},
+}
+
+// crashChild adds sites to the tangle in path until it gets killed
+func crashChild(t *testing.T, engine string, path string) {
+	st, err := crashEngines[engine](path)
+	assert.NoError(t, err)
+	tngl, err := New(Options{Store: st})
+	assert.NoError(t, err)
+	for i := 0; ; i++ {
+		d := dd(strconv.Itoa(os.Getpid()) + "-" + strconv.Itoa(i))
+		ch, _ := d.Hash()
+		vs := []hash.Hash{}
+		for _, r := range tngl.RecommendTips() {
+			vs = append(vs, r.Hash())
+		}
+		s := &site.Site{Content: ch, Type: "dummy", Validates: vs}
+		mine(tngl, s)
+		if err := tngl.Add(&Object{Site: s, Data: d}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			fmt.Println("ready")
+		}
+	}
+}
+
+func TestCrashRecovery(t *testing.T) {
+	if p := os.Getenv(crashEnv); p != "" {
+		crashChild(t, os.Getenv(crashEngineEnv), p)
+		return
 	}
+	for engine, open := range crashEngines {
+		engine, open := engine, open
+		t.Run(engine, func(t *testing.T) {
+			dbpath := path.Join(os.TempDir(), "testcrashrecovery-"+engine)
+			os.RemoveAll(dbpath)
+			defer os.RemoveAll(dbpath)
+			size := 0
+			for _, delay := range []time.Duration{0, 10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond} {
+				cmd := exec.Command(os.Args[0], "-test.run=^TestCrashRecovery$")
+				cmd.Env = append(os.Environ(), crashEnv+"="+dbpath, crashEngineEnv+"="+engine)
+				out, err := cmd.StdoutPipe()
+				assert.NoError(t, err)
+				assert.NoError(t, cmd.Start())
+				ready, err := bufio.NewReader(out).ReadString('\n')
+				assert.NoError(t, err)
+				assert.Equal(t, "ready\n", ready)
+				time.Sleep(delay)
+				assert.NoError(t, cmd.Process.Kill())
+				_ = cmd.Wait()
+
+				st, err := open(dbpath)
+				assert.NoError(t, err)
+				tngl, err := New(Options{Store: st})
+				assert.NoError(t, err)
+				r, err := tngl.Check(false)
+				assert.NoError(t, err)
+				assert.True(t, r.OK(), "%v", r.Problems)
+				assert.True(t, r.Sites > size)
+				size = r.Sites
+				tngl.Close()
+			}
+		})
+	}
+}
+
+func TestSample(t *testing.T) {
+	tngl, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	hs := randomTangle(t, tngl, 20, 1)
+
+	s, err := tngl.Sample(5)
+	assert.NoError(t, err)
+	assert.Len(t, s, 5)
+	seen := map[hash.Hash]bool{}
+	for _, h := range s {
+		assert.False(t, seen[h])
+		seen[h] = true
+		assert.Contains(t, hs, h)
+	}
+	s, err = tngl.Sample(100)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, hs, s)
+	s, err = tngl.Sample(0)
+	assert.NoError(t, err)
+	assert.Empty(t, s)
 }
@@ -0,0 +1,148 @@
+package tangle
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/u-speak/core/tangle/hash"
+)
+
+const (
+	// DefaultAlpha is the default bias of the random walk towards heavier sites
+	DefaultAlpha = 0.5
+	// DefaultDepth is the default number of steps the random walk starts behind the tips
+	DefaultDepth = 10
+)
+
+var (
+	// ErrNoTips is returned when the tangle does not have any tips to select
+	ErrNoTips = errors.New("No tips available")
+	// ErrUnknownStrategy is returned for unknown tip selection strategies
+	ErrUnknownStrategy = errors.New("Unknown tip selection strategy")
+)
+
+// TipSelector chooses the tips new sites should validate
+type TipSelector interface {
+	// SelectTip returns the hash of a single tip of the tangle
+	SelectTip(t *Tangle) (hash.Hash, error)
+}
+
+// NewTipSelector returns the named strategy, either "walk" or "uniform", seeded with the current time
+func NewTipSelector(strategy string, alpha float64, depth int) (TipSelector, error) {
+	src := rand.NewSource(time.Now().UnixNano())
+	switch strategy {
+	case "walk", "":
+		return NewRandomWalk(alpha, depth, src), nil
+	case "uniform":
+		return NewUniformRandom(src), nil
+	}
+	return nil, ErrUnknownStrategy
+}
+
+// RandomWalk selects tips using a random walk towards the tips (MCMC).
+// Each step prefers approvers with a higher cumulative weight, biased by Alpha.
+type RandomWalk struct {
+	// Alpha controls how strongly heavier sites are preferred. 0 results in an unbiased walk
+	Alpha float64
+	// Depth specifies how many steps behind a tip the walk starts
+	Depth int
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewRandomWalk returns a random walk tip selector using the specified source of randomness
+func NewRandomWalk(alpha float64, depth int, src rand.Source) *RandomWalk {
+	return &RandomWalk{Alpha: alpha, Depth: depth, rand: rand.New(src)}
+}
+
+// SelectTip walks from a site Depth steps behind a random tip towards the tips
+func (r *RandomWalk) SelectTip(t *Tangle) (hash.Hash, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tips := t.tipHashes()
+	if len(tips) == 0 {
+		return hash.Hash{}, ErrNoTips
+	}
+	sortHashes(tips)
+	cur := tips[r.rand.Intn(len(tips))]
+	for i := 0; i < r.Depth; i++ {
+		ps := t.graph.parents(cur)
+		if len(ps) == 0 {
+			break
+		}
+		cur = ps[r.rand.Intn(len(ps))]
+	}
+	for {
+		if t.HasTip(cur) {
+			return cur, nil
+		}
+		cs := t.graph.children(cur)
+		if len(cs) == 0 {
+			return cur, nil
+		}
+		cur = cs[r.step(t, cur, cs)]
+	}
+}
+
+// step chooses the index of the next site with a probability proportional to exp(Alpha * H(next)).
+// The weights are shifted by the heaviest child, as H(cur) is far above every child and all terms would underflow to 0
+func (r *RandomWalk) step(t *Tangle, cur hash.Hash, cs []hash.Hash) int {
+	ws := make([]int, len(cs))
+	max := 0
+	for i, c := range cs {
+		// children are always loaded
+		ws[i], _ = t.graph.weight(c)
+		if i == 0 || ws[i] > max {
+			max = ws[i]
+		}
+	}
+	ps := make([]float64, len(cs))
+	sum := 0.0
+	for i, w := range ws {
+		ps[i] = math.Exp(r.Alpha * float64(w-max))
+		sum += ps[i]
+	}
+	x := r.rand.Float64() * sum
+	for i, p := range ps {
+		x -= p
+		if x < 0 {
+			return i
+		}
+	}
+	return len(cs) - 1
+}
+
+// UniformRandom selects one of the current tips with equal probability
+type UniformRandom struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewUniformRandom returns a uniform tip selector using the specified source of randomness
+func NewUniformRandom(src rand.Source) *UniformRandom {
+	return &UniformRandom{rand: rand.New(src)}
+}
+
+// SelectTip returns a random tip
+func (u *UniformRandom) SelectTip(t *Tangle) (hash.Hash, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	tips := t.tipHashes()
+	if len(tips) == 0 {
+		return hash.Hash{}, ErrNoTips
+	}
+	sortHashes(tips)
+	return tips[u.rand.Intn(len(tips))], nil
+}
+
+func sortHashes(hs []hash.Hash) {
+	sort.Slice(hs, func(i, j int) bool {
+		return bytes.Compare(hs[i][:], hs[j][:]) < 0
+	})
+}
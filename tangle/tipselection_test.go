@@ -0,0 +1,168 @@
+package tangle
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u-speak/core/tangle/hash"
+	"github.com/u-speak/core/tangle/site"
+)
+
+func chain(t *testing.T, tngl *Tangle, name string, n int, first, second hash.Hash) []hash.Hash {
+	hs := []hash.Hash{}
+	for i := 0; i < n; i++ {
+		d := dd(name + strconv.Itoa(i))
+		ch, _ := d.Hash()
+		s := &site.Site{Content: ch, Type: "dummy", Validates: []hash.Hash{first, second}}
+		mine(tngl, s)
+		assert.NoError(t, tngl.Inject(&Object{Site: s, Data: d}, true))
+		second, first = first, s.Hash()
+		hs = append(hs, first)
+	}
+	return hs
+}
+
+func TestRandomWalkDeterministic(t *testing.T) {
+	tngl, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	randomTangle(t, tngl, 60, 1)
+
+	tngl.selector = NewRandomWalk(DefaultAlpha, DefaultDepth, rand.NewSource(42))
+	first, err := tngl.SelectTips(20)
+	assert.NoError(t, err)
+	tngl.selector = NewRandomWalk(DefaultAlpha, DefaultDepth, rand.NewSource(42))
+	second, err := tngl.SelectTips(20)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+	for _, h := range first {
+		assert.True(t, tngl.HasTip(h))
+	}
+}
+
+func TestRandomWalkBias(t *testing.T) {
+	tngl, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	gen := tngl.tipHashes()
+	base := chain(t, tngl, "base", 1, gen[0], gen[1])[0]
+	heavy := chain(t, tngl, "heavy", 10, base, gen[0])
+	light := chain(t, tngl, "light", 1, base, gen[1])
+
+	tngl.selector = NewRandomWalk(5, 100, rand.NewSource(1))
+	hs, err := tngl.SelectTips(100)
+	assert.NoError(t, err)
+	n := 0
+	for _, h := range hs {
+		if h == heavy[len(heavy)-1] {
+			n++
+		} else {
+			assert.Equal(t, light[0], h)
+		}
+	}
+	assert.True(t, n > 90, "heavy branch selected %d times", n)
+}
+
+func TestRandomWalkStep(t *testing.T) {
+	tngl := &Tangle{graph: newGraph()}
+	root, heavy, light, lighter := hash.Hash{1}, hash.Hash{2}, hash.Hash{3}, hash.Hash{4}
+	tngl.graph.add(root, nil, 1000, 0)
+	tngl.graph.add(heavy, []hash.Hash{root}, 12, 0)
+	tngl.graph.add(light, []hash.Hash{root}, 11, 0)
+	tngl.graph.add(lighter, []hash.Hash{root}, 10, 0)
+	cs := []hash.Hash{heavy, light, lighter}
+
+	r := NewRandomWalk(1, 0, rand.NewSource(1))
+	n := 30000
+	counts := make([]int, len(cs))
+	for i := 0; i < n; i++ {
+		counts[r.step(tngl, root, cs)]++
+	}
+	sum := 1 + math.Exp(-1) + math.Exp(-2)
+	for i, want := range []float64{1 / sum, math.Exp(-1) / sum, math.Exp(-2) / sum} {
+		assert.InDelta(t, want, float64(counts[i])/float64(n), 0.02, "child %d", i)
+	}
+}
+
+// fixedSelector always selects the same tip
+type fixedSelector struct {
+	tip hash.Hash
+}
+
+func (f *fixedSelector) SelectTip(*Tangle) (hash.Hash, error) {
+	return f.tip, nil
+}
+
+func TestRecommendTips(t *testing.T) {
+	tngl, err := New(Options{Store: ms()})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	gen := tngl.tipHashes()
+	tngl.selector = &fixedSelector{gen[0]}
+	recs := tngl.RecommendTips()
+	assert.ElementsMatch(t, gen, []hash.Hash{recs[0].Hash(), recs[1].Hash()}, "genesis is completed with the other tip")
+
+	tngl.selector = NewRandomWalk(DefaultAlpha, DefaultDepth, rand.NewSource(1))
+	hs := chain(t, tngl, "single", 3, gen[0], gen[1])
+	recs = tngl.RecommendTips()
+	assert.Len(t, recs, MinimumValidations)
+	assert.Equal(t, hs[2], recs[0].Hash())
+
+	chain(t, tngl, "fork", 1, hs[1], hs[0])
+	chain(t, tngl, "fork2", 1, hs[1], hs[0])
+	tngl.selector = NewUniformRandom(rand.NewSource(1))
+	recs = tngl.RecommendTips()
+	assert.True(t, len(recs) >= MinimumValidations)
+	assert.True(t, len(recs) <= MaxRecommendations)
+}
+
+func TestConfidence(t *testing.T) {
+	tngl, err := New(Options{Store: ms(), TipSelector: NewUniformRandom(rand.NewSource(1)), ConfidenceSamples: 50})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	gen := tngl.tipHashes()
+	hs := chain(t, tngl, "confidence", 5, gen[0], gen[1])
+
+	assert.Equal(t, 1.0, tngl.Confidence(hs[0]))
+	assert.Equal(t, 1.0, tngl.Confidence(hs[4]))
+	assert.True(t, tngl.Confirmed(hs[0]))
+	assert.Equal(t, 0.0, tngl.Confidence(hash.Hash{}))
+
+	chain(t, tngl, "fork", 1, hs[2], hs[1])
+	c := tngl.Confidence(hs[4])
+	assert.True(t, c > 0 && c < 1)
+	assert.False(t, tngl.Confirmed(hs[4]))
+	assert.Equal(t, 1.0, tngl.Confidence(hs[2]))
+}
+
+// countingSelector counts the selected tips
+type countingSelector struct {
+	TipSelector
+	n int
+}
+
+func (c *countingSelector) SelectTip(t *Tangle) (hash.Hash, error) {
+	c.n++
+	return c.TipSelector.SelectTip(t)
+}
+
+func TestConfidenceCached(t *testing.T) {
+	sel := &countingSelector{TipSelector: NewUniformRandom(rand.NewSource(1))}
+	tngl, err := New(Options{Store: ms(), TipSelector: sel, ConfidenceSamples: 10})
+	assert.NoError(t, err)
+	defer tngl.Close()
+	gen := tngl.tipHashes()
+	hs := chain(t, tngl, "cached", 2, gen[0], gen[1])
+
+	for _, h := range append(hs, gen...) {
+		tngl.Confidence(h)
+	}
+	assert.Equal(t, 10, sel.n)
+	chain(t, tngl, "cached-more", 1, hs[1], hs[0])
+	tngl.Confidence(hs[0])
+	tngl.Confidence(hs[1])
+	assert.Equal(t, 20, sel.n)
+}